@@ -0,0 +1,60 @@
+package tachymeter
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Apdex is the Application Performance Index of
+// events against a target duration T. Events taking
+// T or less are satisfied, up to 4T tolerating and
+// otherwise frustrated. The score, from 0 to 1, is
+// (Satisfied + Tolerating/2) / events.
+type Apdex struct {
+	T          time.Duration
+	Satisfied  int
+	Tolerating int
+	Frustrated int
+	Score      float64
+}
+
+// MarshalJSON defines the output formatting
+// for the JSON() method.
+func (a *Apdex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		T          string
+		Satisfied  int
+		Tolerating int
+		Frustrated int
+		Score      float64
+	}{
+		T:          a.T.String(),
+		Satisfied:  a.Satisfied,
+		Tolerating: a.Tolerating,
+		Frustrated: a.Frustrated,
+		Score:      a.Score,
+	})
+}
+
+// apdex returns the *Apdex of the
+// summary at target duration t.
+func (s *summary) apdex(t time.Duration) *Apdex {
+	var satisfied, tolerating float64
+	for i, v := range s.times {
+		switch {
+		case v <= t:
+			satisfied += s.w(i)
+		case v <= 4*t:
+			tolerating += s.w(i)
+		}
+	}
+
+	return &Apdex{
+		T:          t,
+		Satisfied:  int(math.Round(satisfied)),
+		Tolerating: int(math.Round(tolerating)),
+		Frustrated: int(math.Round(s.n - satisfied - tolerating)),
+		Score:      (satisfied + tolerating/2) / s.n,
+	}
+}
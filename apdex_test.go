@@ -0,0 +1,48 @@
+package tachymeter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestApdex(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:   10,
+		ApdexT: 50 * time.Millisecond,
+	})
+
+	// 5 satisfied, 3 tolerating
+	// and 2 frustrated.
+	for _, d := range []int{10, 20, 30, 40, 50, 51, 100, 200, 201, 1000} {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+	a := metrics.Apdex
+
+	if a.Satisfied != 5 || a.Tolerating != 3 || a.Frustrated != 2 {
+		t.Errorf("Expected 5, 3, 2, got %d, %d, %d\n", a.Satisfied, a.Tolerating, a.Frustrated)
+	}
+
+	if a.Score != 0.65 {
+		t.Errorf("Expected 0.65, got %f\n", a.Score)
+	}
+
+	if !strings.Contains(metrics.String(), "\nApdex:\t\t0.65 (T=50ms, 5 satisfied, 3 tolerating, 2 frustrated)") {
+		t.Errorf("Expected Apdex in output, got %s\n", metrics.String())
+	}
+
+	if !strings.Contains(metrics.JSON(), `"Apdex":{"T":"50ms","Satisfied":5,"Tolerating":3,"Frustrated":2,"Score":0.65}`) {
+		t.Errorf("Expected Apdex in JSON, got %s\n", metrics.JSON())
+	}
+
+	ta = tachymeter.New(&tachymeter.Config{Size: 10})
+	ta.AddTime(time.Millisecond)
+
+	if metrics := ta.Calc(); metrics.Apdex != nil {
+		t.Errorf("Expected nil, got %v\n", metrics.Apdex)
+	}
+}
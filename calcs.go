@@ -1,7 +1,6 @@
 package tachymeter
 
 import (
-	"fmt"
 	"math"
 	"sort"
 	"sync/atomic"
@@ -11,189 +10,432 @@ import (
 // Calc summarizes Tachymeter sample data
 // and returns it in the form of a *Metrics.
 func (m *Tachymeter) Calc() *Metrics {
-	metrics := &Metrics{}
-	if atomic.LoadUint64(&m.Count) == 0 {
-		return metrics
+	m.Lock()
+	r := m.collect()
+	wallTime := m.WallTime
+	m.Unlock()
+
+	// Sorting happens outside of the lock
+	// so that writers aren't blocked.
+	s := r.summary()
+
+	metrics := s.calc(wallTime, m.HBins, &m.config)
+	metrics.Mode = m.config.Mode
+	metrics.Corrected = atomic.LoadUint32(&m.corrected) == 1
+
+	if o := m.getOutcomes(); o != nil {
+		metrics.setOutcomes(o.success.Calc(), o.failure.Calc())
 	}
 
-	m.Lock()
+	return metrics
+}
+
+// setOutcomes sets the outcome fields of m
+// from the success and failure *Metrics.
+func (m *Metrics) setOutcomes(success, failure *Metrics) {
+	m.Success = success
+	m.Failure = failure
+	m.Errors = failure.Count
+	if n := success.Count + failure.Count; n > 0 {
+		m.ErrorRate = float64(m.Errors) / float64(n)
+	}
+}
 
-	metrics.Samples = int(math.Min(float64(atomic.LoadUint64(&m.Count)), float64(m.Size)))
-	metrics.Count = int(atomic.LoadUint64(&m.Count))
-	times := make(timeSlice, metrics.Samples)
-	copy(times, m.Times[:metrics.Samples])
-	sort.Sort(times)
+// summary is a sorted, backend independent
+// view of recorded event durations.
+type summary struct {
+	times   timeSlice // Sorted event durations.
+	weights []float64 // Events represented by each entry in times. Nil if one each.
+	n       float64   // Sum of weights.
+	count   uint64    // Total number of events observed.
+	samples int       // Number of events included in the summary.
+	method  QuantileMethod
+	// quantile optionally overrides percentile
+	// selection for backends that estimate them.
+	quantile func(q float64) time.Duration
+	// extremes optionally overrides the min and
+	// max for backends that track them exactly.
+	extremes *[2]time.Duration
+}
+
+// newSummary sorts times and returns a
+// *summary. weights may be nil if each
+// duration represents a single event.
+func newSummary(times timeSlice, weights []float64, count uint64) *summary {
+	s := &summary{
+		times:   times,
+		weights: weights,
+		count:   count,
+		samples: len(times),
+	}
+
+	if weights == nil {
+		sort.Sort(times)
+		s.n = float64(len(times))
+		return s
+	}
+
+	sort.Sort(weightedSlice{times: times, weights: weights})
+	for _, w := range weights {
+		s.n += w
+	}
+
+	return s
+}
 
-	metrics.Time.Cumulative = times.cumulative()
+// weightedSlice sorts a timeSlice along
+// with its corresponding weights.
+type weightedSlice struct {
+	times   timeSlice
+	weights []float64
+}
+
+// Satisfy sort for weightedSlice.
+func (p weightedSlice) Len() int           { return len(p.times) }
+func (p weightedSlice) Less(i, j int) bool { return p.times[i] < p.times[j] }
+func (p weightedSlice) Swap(i, j int) {
+	p.times[i], p.times[j] = p.times[j], p.times[i]
+	p.weights[i], p.weights[j] = p.weights[j], p.weights[i]
+}
+
+// calc returns a *Metrics calculated from the
+// summary. A non-zero wallTime is used for rate
+// outputs, hBins specifies the histogram bin
+// count and c the optional outputs.
+func (s *summary) calc(wallTime time.Duration, hBins int, c *Config) *Metrics {
+	s.method = c.Quantile
+	metrics := &Metrics{Distribution: newDistribution(s)}
+	if s.count == 0 || len(s.times) == 0 {
+		return metrics
+	}
+
+	metrics.Samples = s.samples
+	metrics.Count = int(s.count)
+
+	metrics.Time.Cumulative = s.cumulative()
 	var rateTime float64
-	if m.WallTime != 0 {
-		rateTime = float64(metrics.Count) / float64(m.WallTime)
+	if wallTime != 0 {
+		rateTime = float64(metrics.Count) / float64(wallTime)
 	} else {
 		rateTime = float64(metrics.Samples) / float64(metrics.Time.Cumulative)
 	}
 
 	metrics.Rate.Second = rateTime * 1e9
 
-	m.Unlock()
+	metrics.Time.Avg = s.avg()
+	metrics.Time.HMean = s.hMean()
+	metrics.Time.P50 = s.median()
+	metrics.Time.P75 = s.p(0.75)
+	metrics.Time.P95 = s.p(0.95)
+	metrics.Time.P99 = s.p(0.99)
+	metrics.Time.P999 = s.p(0.999)
+	for _, p := range c.Percentiles {
+		metrics.Percentiles = append(metrics.Percentiles, Percentile{
+			P:     p,
+			Value: s.p(p / 100),
+		})
+	}
+	if c.Confidence > 0 && c.Confidence < 1 {
+		s.setIntervals(metrics, c.Confidence)
+	}
+	metrics.Time.Long5p = s.long5p()
+	metrics.Time.Short5p = s.short5p()
+	metrics.Time.Min = s.min()
+	metrics.Time.Max = s.max()
+	metrics.Time.Range = s.srange()
+	metrics.Time.StdDev = s.stdDev()
+
+	if c.ExtendedStats {
+		metrics.Extended = s.extended()
+	}
+	if c.Outliers != OutliersNone {
+		metrics.Outliers = s.outliers(c.Outliers, c.OutlierThreshold)
+	}
+	if c.Trim > 0 && c.Trim < 0.5 {
+		metrics.Trimmed = s.trimmed(c.Trim)
+	}
+	if c.ApdexT > 0 {
+		metrics.Apdex = s.apdex(c.ApdexT)
+	}
 
-	metrics.Time.Avg = times.avg()
-	metrics.Time.HMean = times.hMean()
-	metrics.Time.P50 = times[times.Len()/2]
-	metrics.Time.P75 = times.p(0.75)
-	metrics.Time.P95 = times.p(0.95)
-	metrics.Time.P99 = times.p(0.99)
-	metrics.Time.P999 = times.p(0.999)
-	metrics.Time.Long5p = times.long5p()
-	metrics.Time.Short5p = times.short5p()
-	metrics.Time.Min = times.min()
-	metrics.Time.Max = times.max()
-	metrics.Time.Range = times.srange()
-	metrics.Time.StdDev = times.stdDev()
-
-	metrics.Histogram, metrics.HistogramBinSize = times.hgram(m.HBins)
+	metrics.Bins, metrics.HistogramBinSize = s.histogram(hBins, c)
+	metrics.Histogram = metrics.Bins.Histogram()
 
 	return metrics
 }
 
-// hgram returns a histogram of event durations in
-// b bins, along with the bin size.
-func (ts timeSlice) hgram(b int) (*Histogram, time.Duration) {
-	res := time.Duration(1000)
-	// Interval is the time range / n bins.
-	interval := time.Duration(int64(ts.srange()) / int64(b))
-	high := ts.min() + interval
-	low := ts.min()
-	max := ts.max()
-	hgram := &Histogram{}
-	pos := 1 // Bin position.
-
-	bstring := fmt.Sprintf("%s - %s", low/res*res, high/res*res)
-	bin := map[string]uint64{}
-
-	for _, v := range ts {
-		// If v fits in the current bin,
-		// increment the bin count.
-		if v <= high {
-			bin[bstring]++
-		} else {
-			// If not, prepare the next bin.
-			*hgram = append(*hgram, bin)
-			bin = map[string]uint64{}
-
-			// Update the high/low range values.
-			low = high + time.Nanosecond
-
-			high += interval
-			// if we're going into the
-			// last bin, set high to max.
-			if pos == b-1 {
-				high = max
-			}
-
-			bstring = fmt.Sprintf("%s - %s", low/res*res, high/res*res)
-
-			// The value didn't fit in the previous
-			// bin, so the new bin count should
-			// be incremented.
-			bin[bstring]++
-
-			pos++
-		}
+// w returns the number of events
+// represented by s.times[i].
+func (s *summary) w(i int) float64 {
+	if s.weights == nil {
+		return 1
 	}
+	return s.weights[i]
+}
 
-	*hgram = append(*hgram, bin)
+// hgram returns histogram bins of event durations
+// in b bins of equal width, along with the bin size.
+func (s *summary) hgram(b int) (Bins, time.Duration) {
+	// Interval is the time range / n bins.
+	interval := time.Duration(int64(s.srange()) / int64(b))
+
+	bounds := make([]time.Duration, b)
+	for i := range bounds {
+		bounds[i] = s.min() + time.Duration(i+1)*interval
+	}
+	// The last bin extends to the max, which
+	// absorbs any remainder of the interval.
+	bounds[b-1] = s.max()
 
-	return hgram, interval
+	return s.hgramBounds(s.min(), bounds), interval
 }
 
 // These should be self-explanatory:
 
-func (ts timeSlice) cumulative() time.Duration {
-	var total time.Duration
-	for _, t := range ts {
-		total += t
+func (s *summary) cumulative() time.Duration {
+	var total float64
+	for i, t := range s.times {
+		total += float64(t) * s.w(i)
+	}
+
+	return time.Duration(total)
+}
+
+func (s *summary) hMean() time.Duration {
+	var total float64
+
+	for i, t := range s.times {
+		total += (s.w(i) / float64(t))
 	}
 
-	return total
+	return time.Duration(s.n / total)
 }
 
-func (ts timeSlice) hMean() time.Duration {
+func (s *summary) avg() time.Duration {
 	var total float64
+	for i, t := range s.times {
+		total += float64(t) * s.w(i)
+	}
+	return time.Duration(total / s.n)
+}
+
+// p returns the pth percentile event
+// duration using the summary's
+// QuantileMethod.
+func (s *summary) p(p float64) time.Duration {
+	switch {
+	case s.quantile != nil:
+		return s.quantile(p)
+	case s.method != QuantileRounded:
+		return s.hfQuantile(p)
+	}
+	return s.rank(math.Floor(s.n*p + 0.5))
+}
+
+func (s *summary) median() time.Duration {
+	if s.quantile != nil || s.method != QuantileRounded {
+		return s.p(0.5)
+	}
+	return s.rank(math.Floor(s.n/2) + 1)
+}
+
+// setIntervals sets the confidence intervals
+// of each percentile in m at confidence level.
+func (s *summary) setIntervals(m *Metrics, level float64) {
+	z := math.Sqrt2 * math.Erfinv(level)
+
+	m.Confidence = level
+	m.Intervals = map[string]Interval{
+		"p50":  s.interval(0.5, z),
+		"p75":  s.interval(0.75, z),
+		"p95":  s.interval(0.95, z),
+		"p99":  s.interval(0.99, z),
+		"p999": s.interval(0.999, z),
+	}
+	for _, p := range m.Percentiles {
+		m.Intervals[p.Label()] = s.interval(p.P/100, z)
+	}
+}
 
-	for _, t := range ts {
-		total += (1 / float64(t))
+// interval returns a distribution-free confidence
+// interval for the qth quantile, bounded by the
+// order statistics at ranks n*q -/+ z standard
+// deviations of the binomial distribution of
+// events below it.
+func (s *summary) interval(q, z float64) Interval {
+	d := z * math.Sqrt(s.n*q*(1-q))
+	lo := math.Max(math.Floor(s.n*q-d), 1)
+	hi := math.Min(math.Ceil(s.n*q+d)+1, s.n)
+
+	if s.quantile != nil {
+		return Interval{Low: s.quantile(lo / s.n), High: s.quantile(hi / s.n)}
 	}
 
-	return time.Duration(float64(ts.Len()) / total)
+	return Interval{Low: s.rank(lo), High: s.rank(hi)}
 }
 
-func (ts timeSlice) avg() time.Duration {
-	var total time.Duration
-	for _, t := range ts {
-		total += t
+// rank returns the duration of the
+// rth (1-indexed) smallest event.
+func (s *summary) rank(r float64) time.Duration {
+	if s.weights == nil {
+		i := int(r) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i > len(s.times)-1 {
+			i = len(s.times) - 1
+		}
+		return s.times[i]
+	}
+
+	var c float64
+	for i, w := range s.weights {
+		c += w
+		if c >= r {
+			return s.times[i]
+		}
 	}
-	return time.Duration(int(total) / ts.Len())
+
+	return s.max()
 }
 
-func (ts timeSlice) p(p float64) time.Duration {
-	return ts[int(float64(ts.Len())*p+0.5)-1]
+// rangeAvg returns the average duration of
+// events ranked in the interval (lo, hi].
+func (s *summary) rangeAvg(lo, hi float64) time.Duration {
+	var total, n, c float64
+	for i, t := range s.times {
+		prev := c
+		c += s.w(i)
+		// The portion of this entry's
+		// events that fall within range.
+		in := math.Min(c, hi) - math.Max(prev, lo)
+		if in > 0 {
+			total += float64(t) * in
+			n += in
+		}
+	}
+
+	return time.Duration(total / n)
 }
 
-func (ts timeSlice) stdDev() time.Duration {
-	m := ts.avg()
-	s := 0.00
+func (s *summary) stdDev() time.Duration {
+	m := s.avg()
+	sum := 0.00
 
-	for _, t := range ts {
-		s += math.Pow(float64(m-t), 2)
+	for i, t := range s.times {
+		sum += math.Pow(float64(m-t), 2) * s.w(i)
 	}
 
-	msq := s / float64(ts.Len())
+	msq := sum / s.n
 
 	return time.Duration(math.Sqrt(msq))
 }
 
-func (ts timeSlice) long5p() time.Duration {
-	set := ts[int(float64(ts.Len())*0.95+0.5):]
+func (s *summary) long5p() time.Duration {
+	lo := math.Floor(s.n*0.95 + 0.5)
 
-	if len(set) <= 1 {
-		return ts[ts.Len()-1]
+	if s.n-lo <= 1 {
+		return s.max()
 	}
 
-	var t time.Duration
-	var i int
-	for _, n := range set {
-		t += n
-		i++
+	return s.rangeAvg(lo, s.n)
+}
+
+func (s *summary) short5p() time.Duration {
+	hi := math.Floor(s.n*0.05 + 0.5)
+
+	if hi <= 1 {
+		return s.min()
 	}
 
-	return time.Duration(int(t) / i)
+	return s.rangeAvg(0, hi)
 }
 
-func (ts timeSlice) short5p() time.Duration {
-	set := ts[:int(float64(ts.Len())*0.05+0.5)]
+// extended returns the *ExtendedStats
+// of the summary.
+func (s *summary) extended() *ExtendedStats {
+	e := &ExtendedStats{
+		GeoMean: s.geoMean(),
+		MAD:     s.mad(),
+		IQR:     s.p(0.75) - s.p(0.25),
+	}
+
+	// Central moments.
+	mean := float64(s.avg())
+	var m2, m3, m4 float64
+	for i, t := range s.times {
+		d := float64(t) - mean
+		w := s.w(i)
+		m2 += d * d * w
+		m3 += d * d * d * w
+		m4 += d * d * d * d * w
+	}
+	m2, m3, m4 = m2/s.n, m3/s.n, m4/s.n
 
-	if len(set) <= 1 {
-		return ts[0]
+	if m2 > 0 {
+		e.Skewness = m3 / math.Pow(m2, 1.5)
+		e.Kurtosis = m4/(m2*m2) - 3
 	}
 
-	var t time.Duration
-	var i int
-	for _, n := range set {
-		t += n
-		i++
+	if mean > 0 {
+		e.CV = math.Sqrt(m2) / mean
 	}
 
-	return time.Duration(int(t) / i)
+	return e
 }
 
-func (ts timeSlice) min() time.Duration {
-	return ts[0]
+func (s *summary) geoMean() time.Duration {
+	var total float64
+	for i, t := range s.times {
+		total += math.Log(float64(t)) * s.w(i)
+	}
+
+	return time.Duration(math.Exp(total / s.n))
 }
 
-func (ts timeSlice) max() time.Duration {
-	return ts[ts.Len()-1]
+// mad returns the median absolute
+// deviation from the median.
+func (s *summary) mad() time.Duration {
+	m := s.median()
+
+	devs := make(timeSlice, len(s.times))
+	for i, t := range s.times {
+		if devs[i] = t - m; devs[i] < 0 {
+			devs[i] = -devs[i]
+		}
+	}
+
+	var weights []float64
+	if s.weights != nil {
+		weights = append([]float64(nil), s.weights...)
+	}
+
+	d := newSummary(devs, weights, s.count)
+	d.method = s.method
+
+	return d.median()
+}
+
+func (s *summary) min() time.Duration {
+	if s.quantile != nil {
+		return s.quantile(0)
+	}
+	if s.extremes != nil {
+		return s.extremes[0]
+	}
+	return s.times[0]
+}
+
+func (s *summary) max() time.Duration {
+	if s.quantile != nil {
+		return s.quantile(1)
+	}
+	if s.extremes != nil {
+		return s.extremes[1]
+	}
+	return s.times[len(s.times)-1]
 }
 
-func (ts timeSlice) srange() time.Duration {
-	return ts.max() - ts.min()
+func (s *summary) srange() time.Duration {
+	return s.max() - s.min()
 }
@@ -2,7 +2,9 @@ package tachymeter_test
 
 import (
 	//"fmt"
+	"math"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -156,3 +158,137 @@ func TestCalc(t *testing.T) {
 		t.Errorf("Expected 22.73, got %0.2f\n", metrics.Rate.Second)
 	}
 }
+
+func TestCalcPercentiles(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:        100,
+		Percentiles: []float64{90, 99.5, 10},
+	})
+
+	for i := 1; i <= 100; i++ {
+		ta.AddTime(time.Duration(i) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+
+	if len(metrics.Percentiles) != 3 {
+		t.Fatalf("Expected 3, got %d\n", len(metrics.Percentiles))
+	}
+
+	expected := []struct {
+		label string
+		value time.Duration
+	}{
+		{"p90", 90 * time.Millisecond},
+		{"p99.5", 100 * time.Millisecond},
+		{"p10", 10 * time.Millisecond},
+	}
+
+	for i, e := range expected {
+		p := metrics.Percentiles[i]
+		if p.Label() != e.label {
+			t.Errorf("Expected %s, got %s\n", e.label, p.Label())
+		}
+		if p.Value != e.value {
+			t.Errorf("Expected %d, got %d\n", e.value, p.Value)
+		}
+	}
+
+	if !strings.Contains(metrics.String(), "\np99.5:\t\t100ms\n") {
+		t.Errorf("Expected p99.5 in output, got %s\n", metrics.String())
+	}
+
+	if !strings.Contains(metrics.JSON(), `"Percentiles":[{"P":90,"Value":"90ms"}`) {
+		t.Errorf("Expected Percentiles in JSON, got %s\n", metrics.JSON())
+	}
+}
+
+func TestCalcIntervals(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:        100,
+		Percentiles: []float64{90},
+		Confidence:  0.95,
+	})
+
+	for i := 1; i <= 100; i++ {
+		ta.AddTime(time.Duration(i) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+
+	if metrics.Confidence != 0.95 {
+		t.Errorf("Expected 0.95, got %f\n", metrics.Confidence)
+	}
+
+	expected := map[string]tachymeter.Interval{
+		"p50": {Low: 40 * time.Millisecond, High: 61 * time.Millisecond},
+		"p90": {Low: 84 * time.Millisecond, High: 97 * time.Millisecond},
+		"p99": {Low: 97 * time.Millisecond, High: 100 * time.Millisecond},
+	}
+
+	for label, e := range expected {
+		if got := metrics.Intervals[label]; got != e {
+			t.Errorf("Expected %s %s, got %s\n", label, e, got)
+		}
+	}
+
+	s := metrics.String()
+	if !strings.Contains(s, "\np99:\t\t99ms [97ms, 100ms]\n") {
+		t.Errorf("Expected p99 interval in output, got %s\n", s)
+	}
+
+	if !strings.Contains(s, "\np90:\t\t90ms [84ms, 97ms]\n") {
+		t.Errorf("Expected p90 interval in output, got %s\n", s)
+	}
+
+	ta = tachymeter.New(&tachymeter.Config{Size: 100})
+	ta.AddTime(time.Millisecond)
+
+	if metrics := ta.Calc(); metrics.Intervals != nil {
+		t.Errorf("Expected no intervals, got %v\n", metrics.Intervals)
+	}
+}
+
+func TestCalcExtended(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 10})
+	ta.AddTime(time.Millisecond)
+
+	if metrics := ta.Calc(); metrics.Extended != nil {
+		t.Errorf("Expected nil, got %v\n", metrics.Extended)
+	}
+
+	ta = tachymeter.New(&tachymeter.Config{
+		Size:          10,
+		ExtendedStats: true,
+	})
+
+	for _, d := range []int{13, 1, 2, 40, 1, 5, 3, 8, 1, 2} {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	e := ta.Calc().Extended
+
+	if e.GeoMean != 3465169 {
+		t.Errorf("Expected 3465169, got %d\n", e.GeoMean)
+	}
+
+	if e.MAD != 2*time.Millisecond {
+		t.Errorf("Expected 2ms, got %s\n", e.MAD)
+	}
+
+	if e.IQR != 7*time.Millisecond {
+		t.Errorf("Expected 7ms, got %s\n", e.IQR)
+	}
+
+	if math.Abs(e.Skewness-2.214637) > 1e-6 {
+		t.Errorf("Expected 2.214637, got %f\n", e.Skewness)
+	}
+
+	if math.Abs(e.Kurtosis-3.569916) > 1e-6 {
+		t.Errorf("Expected 3.569916, got %f\n", e.Kurtosis)
+	}
+
+	if math.Abs(e.CV-1.500462) > 1e-6 {
+		t.Errorf("Expected 1.500462, got %f\n", e.CV)
+	}
+}
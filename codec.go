@@ -0,0 +1,131 @@
+package tachymeter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrCorrupt is returned when decoding
+// malformed or inconsistent data.
+var ErrCorrupt = errors.New("tachymeter: corrupt encoding")
+
+// encoder writes values in a
+// compact varint based format.
+type encoder struct {
+	bytes.Buffer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.Write(e.buf[:n])
+}
+
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.Write(e.buf[:n])
+}
+
+func (e *encoder) float(v float64) {
+	e.uvarint(math.Float64bits(v))
+}
+
+func (e *encoder) duration(t time.Duration) {
+	e.varint(int64(t))
+}
+
+// bytes writes a length prefixed b.
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.Write(b)
+}
+
+// decoder reads values written by an encoder.
+// Decoding stops at the first error, which is
+// held in err; subsequent reads return zero
+// values.
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func newDecoder(data []byte) *decoder {
+	return &decoder{r: bytes.NewReader(data)}
+}
+
+func (d *decoder) byte() byte {
+	var b byte
+	if d.err == nil {
+		b, d.err = d.r.ReadByte()
+	}
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	var v uint64
+	if d.err == nil {
+		v, d.err = binary.ReadUvarint(d.r)
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	var v int64
+	if d.err == nil {
+		v, d.err = binary.ReadVarint(d.r)
+	}
+	return v
+}
+
+func (d *decoder) float() float64 {
+	return math.Float64frombits(d.uvarint())
+}
+
+// weight reads a float, failing unless it's
+// positive and finite.
+func (d *decoder) weight() float64 {
+	w := d.float()
+	if !(w > 0) || math.IsInf(w, 1) {
+		d.fail()
+	}
+	return w
+}
+
+func (d *decoder) duration() time.Duration {
+	return time.Duration(d.varint())
+}
+
+// length reads a length prefix, failing if it
+// exceeds the number of bytes remaining, which
+// bounds allocations sized by it.
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(d.r.Len()) {
+		d.err = ErrCorrupt
+	}
+	if d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// fail sets err to ErrCorrupt if
+// no error has occurred.
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = ErrCorrupt
+	}
+}
+
+// bytes reads a length prefixed byte slice.
+func (d *decoder) bytes() []byte {
+	b := make([]byte, d.length())
+	if d.err == nil {
+		_, d.err = io.ReadFull(d.r, b)
+	}
+	return b
+}
@@ -0,0 +1,371 @@
+package tachymeter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ddsketchVersion is the DDSketch
+// binary encoding format version.
+const ddsketchVersion byte = 1
+
+var (
+	// ErrModeMismatch is returned when an operation
+	// requires a Tachymeter with a different Mode.
+	ErrModeMismatch = errors.New("tachymeter: mode mismatch")
+	// ErrAccuracyMismatch is returned when merging
+	// DDSketches with different relative accuracies.
+	ErrAccuracyMismatch = errors.New("tachymeter: relative accuracy mismatch")
+)
+
+// DDSketch is a relative error quantile sketch of
+// event durations. Events are counted in logarithmically
+// sized buckets such that every reported percentile is
+// within the configured relative accuracy of the true
+// value, regardless of the distribution. DDSketches
+// are serializable and losslessly mergeable.
+// See https://arxiv.org/abs/1908.10693.
+type DDSketch struct {
+	alpha     float64 // Relative accuracy.
+	gamma     float64
+	lnGamma   float64
+	bins      []uint64 // Counts of buckets offset, offset+1, ...
+	offset    int
+	zeroCount uint64 // Events too small to index, i.e. < 1ns.
+	count     uint64
+	min, max  time.Duration
+}
+
+// newDDSketch returns a *DDSketch with the
+// relative accuracy alpha. alpha defaults to
+// 0.01 if it's outside of (0, 1).
+func newDDSketch(alpha float64) *DDSketch {
+	if alpha <= 0 || alpha >= 1 {
+		alpha = 0.01
+	}
+
+	gamma := (1 + alpha) / (1 - alpha)
+
+	return &DDSketch{
+		alpha:   alpha,
+		gamma:   gamma,
+		lnGamma: math.Log(gamma),
+		min:     math.MaxInt64,
+	}
+}
+
+// index returns the bucket index for t.
+func (s *DDSketch) index(t time.Duration) int {
+	return int(math.Ceil(math.Log(float64(t)) / s.lnGamma))
+}
+
+// value returns the representative duration
+// of bucket i, which is within alpha of all
+// values counted in it.
+func (s *DDSketch) value(i int) time.Duration {
+	return time.Duration(2 * math.Pow(s.gamma, float64(i)) / (s.gamma + 1))
+}
+
+func (s *DDSketch) add(t time.Duration) {
+	s.count++
+	if t < s.min {
+		s.min = t
+	}
+	if t > s.max {
+		s.max = t
+	}
+
+	if t < 1 {
+		s.zeroCount++
+		return
+	}
+
+	s.addBin(s.index(t), 1)
+}
+
+// addBin adds n to the count of bucket
+// i, growing the bins as needed.
+func (s *DDSketch) addBin(i int, n uint64) {
+	switch {
+	case len(s.bins) == 0:
+		s.bins = []uint64{0}
+		s.offset = i
+	case i < s.offset:
+		grown := make([]uint64, len(s.bins)+s.offset-i)
+		copy(grown[s.offset-i:], s.bins)
+		s.bins = grown
+		s.offset = i
+	case i >= s.offset+len(s.bins):
+		grown := make([]uint64, i-s.offset+1)
+		copy(grown, s.bins)
+		s.bins = grown
+	}
+
+	s.bins[i-s.offset] += n
+}
+
+func (s *DDSketch) reset() {
+	s.bins = s.bins[:0]
+	s.offset = 0
+	s.zeroCount = 0
+	s.count = 0
+	s.min, s.max = math.MaxInt64, 0
+}
+
+func (s *DDSketch) clone() recorder {
+	c := *s
+	c.bins = append([]uint64(nil), s.bins...)
+
+	return &c
+}
+
+func (s *DDSketch) merge(o recorder) {
+	od := o.(*DDSketch)
+	for i, n := range od.bins {
+		if n > 0 {
+			s.addBin(od.offset+i, n)
+		}
+	}
+
+	s.zeroCount += od.zeroCount
+	s.count += od.count
+	if od.min < s.min {
+		s.min = od.min
+	}
+	if od.max > s.max {
+		s.max = od.max
+	}
+}
+
+// quantile returns the representative duration
+// of the bucket holding the event at quantile q.
+func (s *DDSketch) quantile(q float64) time.Duration {
+	switch {
+	case s.count == 0:
+		return 0
+	case q <= 0:
+		return s.min
+	case q >= 1:
+		return s.max
+	}
+
+	rank := q * float64(s.count-1)
+	c := float64(s.zeroCount)
+	if c > rank {
+		return 0
+	}
+
+	for i, n := range s.bins {
+		c += float64(n)
+		if c > rank {
+			v := s.value(s.offset + i)
+			// Representative values of the extreme
+			// buckets may fall outside of the
+			// observed range.
+			if v < s.min {
+				v = s.min
+			}
+			if v > s.max {
+				v = s.max
+			}
+			return v
+		}
+	}
+
+	return s.max
+}
+
+// summary returns the representative
+// bucket durations weighted by their counts.
+func (s *DDSketch) summary() *summary {
+	var times timeSlice
+	var weights []float64
+
+	if s.zeroCount > 0 {
+		times = append(times, 0)
+		weights = append(weights, float64(s.zeroCount))
+	}
+
+	for i, n := range s.bins {
+		if n == 0 {
+			continue
+		}
+		times = append(times, s.value(s.offset+i))
+		weights = append(weights, float64(n))
+	}
+
+	sum := newSummary(times, weights, s.count)
+	sum.samples = int(s.count)
+	sum.quantile = s.quantile
+
+	return sum
+}
+
+// Merge losslessly merges o into s. Both
+// sketches must have the same relative accuracy.
+func (s *DDSketch) Merge(o *DDSketch) error {
+	if s.alpha != o.alpha {
+		return ErrAccuracyMismatch
+	}
+
+	s.merge(o)
+
+	return nil
+}
+
+// Count returns the number of events
+// counted by the sketch.
+func (s *DDSketch) Count() uint64 {
+	return s.count
+}
+
+// MarshalBinary encodes the sketch in a
+// versioned binary format.
+func (s *DDSketch) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.WriteByte(ddsketchVersion)
+	s.encode(e)
+
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a sketch encoded
+// with MarshalBinary, replacing the contents
+// of s, including its relative accuracy.
+func (s *DDSketch) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data)
+
+	version := d.byte()
+	if d.err != nil {
+		return d.err
+	}
+	if version != ddsketchVersion {
+		return fmt.Errorf("tachymeter: unsupported DDSketch encoding version %d", version)
+	}
+
+	// s takes the relative accuracy
+	// of the decoded sketch.
+	ds := &DDSketch{}
+	ds.decode(d)
+	if d.err != nil {
+		return d.err
+	}
+	*s = *ds
+
+	return nil
+}
+
+func (s *DDSketch) encode(e *encoder) {
+	e.float(s.alpha)
+	e.uvarint(s.count)
+	e.uvarint(s.zeroCount)
+	e.duration(s.min)
+	e.duration(s.max)
+	e.varint(int64(s.offset))
+	e.uvarint(uint64(len(s.bins)))
+	for _, n := range s.bins {
+		e.uvarint(n)
+	}
+}
+
+// decode replaces the contents of s with the
+// decoded sketch, failing with ErrAccuracyMismatch
+// if s has a different relative accuracy. A zero
+// value s takes the decoded relative accuracy.
+func (s *DDSketch) decode(d *decoder) {
+	alpha := d.float()
+	count := d.uvarint()
+	zeroCount := d.uvarint()
+	min := d.duration()
+	max := d.duration()
+	offset := d.varint()
+
+	bins := make([]uint64, d.length())
+	for i := range bins {
+		bins[i] = d.uvarint()
+	}
+
+	if d.err != nil {
+		return
+	}
+
+	if math.IsNaN(alpha) || alpha <= 0 || alpha >= 1 {
+		d.fail()
+		return
+	}
+	if s.alpha != 0 && s.alpha != alpha {
+		d.err = ErrAccuracyMismatch
+		return
+	}
+
+	ds := newDDSketch(alpha)
+
+	// Bins must lie within the range of indexable
+	// durations, which bounds the growth of sketches
+	// that ds is merged into.
+	last := int64(ds.index(math.MaxInt64))
+	if offset < 0 || offset > last || offset+int64(len(bins))-1 > last {
+		d.fail()
+		return
+	}
+
+	n := zeroCount
+	for _, c := range bins {
+		if n+c < n {
+			d.fail()
+			return
+		}
+		n += c
+	}
+	if n != count {
+		d.fail()
+		return
+	}
+
+	ds.count = count
+	ds.zeroCount = zeroCount
+	ds.min = min
+	ds.max = max
+	ds.offset = int(offset)
+	ds.bins = bins
+	*s = *ds
+}
+
+// Sketch returns a copy of the events recorded
+// by a ModeDDSketch Tachymeter as a *DDSketch.
+func (m *Tachymeter) Sketch() (*DDSketch, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.shards == nil {
+		return nil, ErrModeMismatch
+	}
+
+	s, ok := m.collect().(*DDSketch)
+	if !ok {
+		return nil, ErrModeMismatch
+	}
+
+	return s, nil
+}
+
+// MergeSketch merges the events held by s into
+// a ModeDDSketch Tachymeter.
+func (m *Tachymeter) MergeSketch(s *DDSketch) error {
+	if m.shards == nil {
+		return ErrModeMismatch
+	}
+
+	sh := m.shards[0]
+	sh.Lock()
+	defer sh.Unlock()
+
+	d, ok := sh.rec.(*DDSketch)
+	if !ok {
+		return ErrModeMismatch
+	}
+
+	return d.Merge(s)
+}
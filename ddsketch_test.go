@@ -0,0 +1,189 @@
+package tachymeter_test
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestDDSketch(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Mode:             tachymeter.ModeDDSketch,
+			RelativeAccuracy: 0.01,
+			Shards:           shards,
+		})
+
+		// 1µs..100ms in 1µs steps.
+		for i := 1; i <= 100000; i++ {
+			ta.AddTime(time.Duration(i) * time.Microsecond)
+		}
+
+		metrics := ta.Calc()
+
+		if metrics.Count != 100000 {
+			t.Errorf("Expected 100000, got %d\n", metrics.Count)
+		}
+
+		if metrics.Time.Min != time.Microsecond {
+			t.Errorf("Expected 1µs, got %s\n", metrics.Time.Min)
+		}
+
+		if metrics.Time.Max != 100*time.Millisecond {
+			t.Errorf("Expected 100ms, got %s\n", metrics.Time.Max)
+		}
+
+		expected := map[string][2]time.Duration{
+			"p50":  {metrics.Time.P50, 50 * time.Millisecond},
+			"p75":  {metrics.Time.P75, 75 * time.Millisecond},
+			"p95":  {metrics.Time.P95, 95 * time.Millisecond},
+			"p99":  {metrics.Time.P99, 99 * time.Millisecond},
+			"p999": {metrics.Time.P999, 99900 * time.Microsecond},
+		}
+
+		for k, v := range expected {
+			if !within(v[0], v[1], 0.01) {
+				t.Errorf("%s: expected %s, got %s\n", k, v[1], v[0])
+			}
+		}
+	}
+}
+
+func TestDDSketchRelativeError(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Mode:             tachymeter.ModeDDSketch,
+		RelativeAccuracy: 0.02,
+	})
+
+	// A long tailed distribution.
+	r := rand.New(rand.NewSource(1))
+	times := make([]time.Duration, 10001)
+	for i := range times {
+		times[i] = time.Duration(r.ExpFloat64() * float64(time.Millisecond))
+		ta.AddTime(times[i])
+	}
+
+	metrics := ta.Calc()
+
+	// With 10001 events, p99 is exactly
+	// the event with rank 9901.
+	sorted := tachymeter.New(&tachymeter.Config{Size: len(times)})
+	for _, d := range times {
+		sorted.AddTime(d)
+	}
+	exact := sorted.Calc()
+
+	if !within(metrics.Time.P99, exact.Time.P99, 0.02) {
+		t.Errorf("Expected %s within 2%%, got %s\n", exact.Time.P99, metrics.Time.P99)
+	}
+}
+
+func TestDDSketchSerialization(t *testing.T) {
+	c := &tachymeter.Config{Mode: tachymeter.ModeDDSketch}
+	a, b := tachymeter.New(c), tachymeter.New(c)
+
+	for i := 1; i <= 100; i++ {
+		a.AddTime(time.Duration(i) * time.Millisecond)
+		b.AddTime(time.Duration(i+100) * time.Millisecond)
+	}
+
+	s, err := a.Sketch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ship a's sketch to b.
+	received := &tachymeter.DDSketch{}
+	if err := received.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Count() != 100 {
+		t.Errorf("Expected 100, got %d\n", received.Count())
+	}
+
+	if err := b.MergeSketch(received); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := b.Calc()
+
+	if metrics.Count != 200 {
+		t.Errorf("Expected 200, got %d\n", metrics.Count)
+	}
+
+	if metrics.Time.Min != time.Millisecond {
+		t.Errorf("Expected 1ms, got %s\n", metrics.Time.Min)
+	}
+
+	if metrics.Time.Max != 200*time.Millisecond {
+		t.Errorf("Expected 200ms, got %s\n", metrics.Time.Max)
+	}
+
+	if !within(metrics.Time.P50, 100*time.Millisecond, 0.01) {
+		t.Errorf("Expected 100ms, got %s\n", metrics.Time.P50)
+	}
+
+	// Mismatched accuracy and modes.
+	other := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeDDSketch, RelativeAccuracy: 0.05})
+	if err := other.MergeSketch(received); err != tachymeter.ErrAccuracyMismatch {
+		t.Errorf("Expected ErrAccuracyMismatch, got %v\n", err)
+	}
+
+	window := tachymeter.New(&tachymeter.Config{Size: 10})
+	if _, err := window.Sketch(); err != tachymeter.ErrModeMismatch {
+		t.Errorf("Expected ErrModeMismatch, got %v\n", err)
+	}
+
+	if err := received.UnmarshalBinary([]byte{99}); err == nil {
+		t.Error("Expected version error")
+	}
+}
+
+// encodeSketch returns a DDSketch encoding
+// of the given fields.
+func encodeSketch(alpha float64, count, zeroCount uint64, offset int64, bins ...uint64) []byte {
+	b := []byte{1}
+	b = binary.AppendUvarint(b, math.Float64bits(alpha))
+	b = binary.AppendUvarint(b, count)
+	b = binary.AppendUvarint(b, zeroCount)
+	b = binary.AppendVarint(b, int64(time.Millisecond))
+	b = binary.AppendVarint(b, int64(time.Millisecond))
+	b = binary.AppendVarint(b, offset)
+	b = binary.AppendUvarint(b, uint64(len(bins)))
+	for _, n := range bins {
+		b = binary.AppendUvarint(b, n)
+	}
+
+	return b
+}
+
+func TestDDSketchCorrupt(t *testing.T) {
+	valid := encodeSketch(0.01, 1, 0, 691, 1)
+	if err := (&tachymeter.DDSketch{}).UnmarshalBinary(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"NaN alpha":      encodeSketch(math.NaN(), 1, 0, 691, 1),
+		"infinite alpha": encodeSketch(math.Inf(1), 1, 0, 691, 1),
+		"zero alpha":     encodeSketch(0, 1, 0, 691, 1),
+		"alpha of 1":     encodeSketch(1, 1, 0, 691, 1),
+		"negative index": encodeSketch(0.01, 1, 0, -1, 1),
+		"far index":      encodeSketch(0.01, 1, 0, 1<<40, 1),
+		"count mismatch": encodeSketch(0.01, 5, 0, 691, 1),
+	} {
+		if err := (&tachymeter.DDSketch{}).UnmarshalBinary(data); err != tachymeter.ErrCorrupt {
+			t.Errorf("%s: Expected ErrCorrupt, got %v\n", name, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package tachymeter
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// decayRescale is the decay exponent beyond
+// which weights are rescaled to a new landmark
+// to avoid overflow.
+const decayRescale = 100
+
+// decaySample is an event duration with
+// its forward decay weight and priority.
+type decaySample struct {
+	t        time.Duration
+	weight   float64
+	priority float64
+}
+
+// decayHeap is a min-heap of
+// decaySamples by priority.
+type decayHeap []decaySample
+
+// Satisfy heap.Interface for decayHeap.
+func (h decayHeap) Len() int            { return len(h) }
+func (h decayHeap) Less(i, j int) bool  { return h[i].priority < h[j].priority }
+func (h decayHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *decayHeap) Push(x interface{}) { *h = append(*h, x.(decaySample)) }
+func (h *decayHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// decay is an exponentially decaying priority
+// reservoir of up to size events, using forward
+// decay relative to a landmark time. Each event is
+// weighted by exp(alpha * age) at the time it's
+// recorded, so recent events dominate the sample
+// without older events being discarded abruptly.
+// See http://dimacs.rutgers.edu/~graham/pubs/papers/fwddecay.pdf.
+type decay struct {
+	size     int
+	alpha    float64
+	samples  decayHeap
+	count    uint64
+	landmark time.Time
+	rand     *rand.Rand
+}
+
+// newDecay returns a *decay that holds up to
+// size events with the decay rate specified
+// in c.
+func newDecay(c *Config, size int) *decay {
+	alpha := c.Alpha
+	if alpha <= 0 {
+		alpha = 0.015
+	}
+
+	return &decay{
+		size:     size,
+		alpha:    alpha,
+		landmark: time.Now(),
+		rand:     rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (d *decay) add(t time.Duration) {
+	now := time.Now()
+	if d.alpha*now.Sub(d.landmark).Seconds() > decayRescale {
+		d.rescale(now)
+	}
+
+	d.count++
+
+	weight := math.Exp(d.alpha * now.Sub(d.landmark).Seconds())
+	s := decaySample{
+		t:        t,
+		weight:   weight,
+		priority: weight / (1 - d.rand.Float64()),
+	}
+
+	d.push(s)
+}
+
+// push adds s to the reservoir, evicting the
+// lowest priority sample if it's full.
+func (d *decay) push(s decaySample) {
+	if len(d.samples) < d.size {
+		heap.Push(&d.samples, s)
+		return
+	}
+
+	if s.priority > d.samples[0].priority {
+		d.samples[0] = s
+		heap.Fix(&d.samples, 0)
+	}
+}
+
+// rescale moves the landmark to l, scaling all
+// weights and priorities accordingly. Relative
+// ordering of priorities is unaffected.
+func (d *decay) rescale(l time.Time) {
+	f := math.Exp(-d.alpha * l.Sub(d.landmark).Seconds())
+	for i := range d.samples {
+		d.samples[i].weight *= f
+		d.samples[i].priority *= f
+	}
+
+	d.landmark = l
+}
+
+func (d *decay) reset() {
+	d.samples = d.samples[:0]
+	d.count = 0
+	d.landmark = time.Now()
+}
+
+func (d *decay) clone() recorder {
+	c := *d
+	c.samples = make(decayHeap, len(d.samples), cap(d.samples))
+	copy(c.samples, d.samples)
+
+	return &c
+}
+
+// merge adds the samples of o to d, retaining the
+// highest priority samples of both. The merged
+// reservoir holds up to the combined size of both.
+func (d *decay) merge(o recorder) {
+	od := o.clone().(*decay)
+
+	// Bring both reservoirs to the
+	// latest landmark.
+	if od.landmark.After(d.landmark) {
+		d.rescale(od.landmark)
+	} else {
+		od.rescale(d.landmark)
+	}
+
+	d.size += od.size
+	d.count += od.count
+	for _, s := range od.samples {
+		d.push(s)
+	}
+}
+
+func (d *decay) encode(e *encoder) {
+	e.uvarint(d.count)
+	e.varint(d.landmark.UnixNano())
+	e.uvarint(uint64(len(d.samples)))
+	for _, s := range d.samples {
+		e.duration(s.t)
+		e.float(s.weight)
+		e.float(s.priority)
+	}
+}
+
+// decode restores the samples in their
+// encoded order, which is heap ordered.
+func (d *decay) decode(dec *decoder) {
+	d.count = dec.uvarint()
+	d.landmark = time.Unix(0, dec.varint())
+
+	n := dec.length()
+	if n > d.size {
+		dec.fail()
+		return
+	}
+
+	d.samples = d.samples[:0]
+	for ; n > 0; n-- {
+		d.samples = append(d.samples, decaySample{
+			t:        dec.duration(),
+			weight:   dec.weight(),
+			priority: dec.weight(),
+		})
+	}
+}
+
+// summary returns the sampled events weighted by
+// their decay weights, normalized such that the
+// weights sum to the sample count.
+func (d *decay) summary() *summary {
+	times := make(timeSlice, len(d.samples))
+	weights := make([]float64, len(d.samples))
+
+	var total float64
+	for _, s := range d.samples {
+		total += s.weight
+	}
+
+	scale := float64(len(d.samples)) / total
+	for i, s := range d.samples {
+		times[i] = s.t
+		weights[i] = s.weight * scale
+	}
+
+	return newSummary(times, weights, d.count)
+}
@@ -0,0 +1,90 @@
+package tachymeter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestDecay(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Size:   8000,
+			Mode:   tachymeter.ModeDecay,
+			Alpha:  10,
+			Shards: shards,
+		})
+
+		for i := 0; i < 1000; i++ {
+			ta.AddTime(time.Millisecond)
+		}
+
+		// Older events lose weight at
+		// a rate of e^(alpha*t).
+		time.Sleep(300 * time.Millisecond)
+
+		for i := 0; i < 1000; i++ {
+			ta.AddTime(100 * time.Millisecond)
+		}
+
+		metrics := ta.Calc()
+
+		if metrics.Mode != tachymeter.ModeDecay {
+			t.Errorf("Expected decay, got %s\n", metrics.Mode)
+		}
+
+		if metrics.Count != 2000 {
+			t.Errorf("Expected 2000, got %d\n", metrics.Count)
+		}
+
+		if metrics.Samples != 2000 {
+			t.Errorf("Expected 2000, got %d\n", metrics.Samples)
+		}
+
+		// An unweighted avg. would be 50.5ms. Recent
+		// events are weighted at least e^3 higher.
+		if metrics.Time.Avg < 90*time.Millisecond {
+			t.Errorf("Expected > 90ms, got %s\n", metrics.Time.Avg)
+		}
+
+		if metrics.Time.P50 != 100*time.Millisecond {
+			t.Errorf("Expected 100ms, got %s\n", metrics.Time.P50)
+		}
+
+		// The older events are still included.
+		if metrics.Time.Min != time.Millisecond {
+			t.Errorf("Expected 1ms, got %s\n", metrics.Time.Min)
+		}
+	}
+}
+
+func TestDecayEviction(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:  100,
+		Mode:  tachymeter.ModeDecay,
+		Alpha: 10,
+	})
+
+	for i := 0; i < 1000; i++ {
+		ta.AddTime(time.Millisecond)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	for i := 0; i < 1000; i++ {
+		ta.AddTime(100 * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+
+	if metrics.Samples != 100 {
+		t.Errorf("Expected 100, got %d\n", metrics.Samples)
+	}
+
+	// Newer events have e^5 higher priority
+	// and should have displaced the older.
+	if metrics.Time.P50 != 100*time.Millisecond {
+		t.Errorf("Expected 100ms, got %s\n", metrics.Time.P50)
+	}
+}
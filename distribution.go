@@ -0,0 +1,92 @@
+package tachymeter
+
+import (
+	"sort"
+	"time"
+)
+
+// Distribution is the sorted distribution of the event
+// durations summarized by a *Metrics, which answers
+// queries at arbitrary points, e.g. the fraction of
+// events that completed within 100ms.
+type Distribution struct {
+	s *summary
+	// cumulative holds the cumulative
+	// weights of s.times.
+	cumulative []float64
+}
+
+// CDFPoint is a point of an empirical cumulative
+// distribution function: the Fraction of events
+// with durations of Value or less.
+type CDFPoint struct {
+	Value    time.Duration
+	Fraction float64
+}
+
+// newDistribution returns a *Distribution
+// of the events in s.
+func newDistribution(s *summary) *Distribution {
+	d := &Distribution{
+		s:          s,
+		cumulative: make([]float64, len(s.times)),
+	}
+
+	var c float64
+	for i := range s.times {
+		c += s.w(i)
+		d.cumulative[i] = c
+	}
+
+	return d
+}
+
+// Quantile returns the event duration at quantile
+// q, from 0 to 1, using the configured
+// QuantileMethod.
+func (d *Distribution) Quantile(q float64) time.Duration {
+	if len(d.s.times) == 0 {
+		return 0
+	}
+	return d.s.p(q)
+}
+
+// CDF returns the fraction of events
+// with durations of t or less.
+func (d *Distribution) CDF(t time.Duration) float64 {
+	times := d.s.times
+	i := sort.Search(len(times), func(i int) bool { return times[i] > t })
+	if i == 0 {
+		return 0
+	}
+
+	return d.cumulative[i-1] / d.s.n
+}
+
+// PercentBelow returns the percentage of
+// events with durations of t or less.
+func (d *Distribution) PercentBelow(t time.Duration) float64 {
+	return d.CDF(t) * 100
+}
+
+// ECDF returns the empirical cumulative distribution
+// function of event durations as a series of points,
+// one per distinct duration in ascending order.
+func (d *Distribution) ECDF() []CDFPoint {
+	times := d.s.times
+
+	var points []CDFPoint
+	for i, t := range times {
+		// Only the last of equal
+		// durations is included.
+		if i < len(times)-1 && times[i+1] == t {
+			continue
+		}
+		points = append(points, CDFPoint{
+			Value:    t,
+			Fraction: d.cumulative[i] / d.s.n,
+		})
+	}
+
+	return points
+}
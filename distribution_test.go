@@ -0,0 +1,60 @@
+package tachymeter_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestDistribution(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 10})
+
+	for _, d := range []int{40, 10, 20, 20, 30} {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	d := ta.Calc().Distribution
+
+	cdf := map[time.Duration]float64{
+		5 * time.Millisecond:  0,
+		10 * time.Millisecond: 0.2,
+		25 * time.Millisecond: 0.6,
+		40 * time.Millisecond: 1,
+		time.Second:           1,
+	}
+
+	for v, e := range cdf {
+		if got := d.CDF(v); got != e {
+			t.Errorf("Expected %f, got %f\n", e, got)
+		}
+	}
+
+	if got := d.PercentBelow(30 * time.Millisecond); got != 80 {
+		t.Errorf("Expected 80, got %f\n", got)
+	}
+
+	if got := d.Quantile(0.5); got != 20*time.Millisecond {
+		t.Errorf("Expected 20ms, got %s\n", got)
+	}
+
+	expected := []tachymeter.CDFPoint{
+		{Value: 10 * time.Millisecond, Fraction: 0.2},
+		{Value: 20 * time.Millisecond, Fraction: 0.6},
+		{Value: 30 * time.Millisecond, Fraction: 0.8},
+		{Value: 40 * time.Millisecond, Fraction: 1},
+	}
+
+	if got := d.ECDF(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v\n", expected, got)
+	}
+}
+
+func TestDistributionEmpty(t *testing.T) {
+	d := tachymeter.New(&tachymeter.Config{Size: 10}).Calc().Distribution
+
+	if d.CDF(time.Second) != 0 || d.Quantile(0.5) != 0 || len(d.ECDF()) != 0 {
+		t.Error("Expected an empty distribution")
+	}
+}
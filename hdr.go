@@ -0,0 +1,253 @@
+package tachymeter
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// hdr is a log-linear HDR histogram of event
+// durations in nanoseconds. Values are counted
+// in buckets of exponentially increasing size,
+// each split into linear sub-buckets sized to
+// maintain the configured number of significant
+// digits. See http://hdrhistogram.org.
+type hdr struct {
+	lowest, highest int64
+
+	unitMagnitude               uint
+	subBucketHalfCountMagnitude uint
+	subBucketCount              int64
+	subBucketHalfCount          int64
+	subBucketMask               int64
+
+	counts   []uint64
+	total    uint64
+	min, max int64
+}
+
+// newHDR returns an *hdr configured with
+// the HDR parameters specified in c.
+func newHDR(c *Config) *hdr {
+	h, n := hdrLayout(c)
+	h.counts = make([]uint64, n)
+	h.min, h.max = math.MaxInt64, 0
+
+	return h
+}
+
+// hdrLayout returns an *hdr configured with the
+// HDR parameters specified in c, without counts,
+// and the number of counts it requires.
+func hdrLayout(c *Config) (*hdr, int) {
+	digits := c.HDRDigits
+	switch {
+	case digits == 0:
+		digits = 3
+	case digits < 1:
+		digits = 1
+	case digits > 5:
+		digits = 5
+	}
+
+	lowest := int64(c.HDRLowest)
+	if lowest < 1 {
+		lowest = 1
+	}
+
+	highest := int64(c.HDRHighest)
+	if highest == 0 {
+		highest = int64(time.Hour)
+	}
+	if highest < 2*lowest {
+		highest = 2 * lowest
+	}
+
+	h := &hdr{lowest: lowest, highest: highest}
+
+	// The largest value with single unit resolution
+	// determines the sub-bucket count needed to
+	// maintain the requested precision.
+	largest := 2 * int64(math.Pow10(digits))
+	subBucketCountMagnitude := uint(math.Ceil(math.Log2(float64(largest))))
+	h.subBucketHalfCountMagnitude = subBucketCountMagnitude - 1
+	h.unitMagnitude = uint(bits.Len64(uint64(lowest)) - 1)
+	h.subBucketCount = 1 << (h.subBucketHalfCountMagnitude + 1)
+	h.subBucketHalfCount = h.subBucketCount / 2
+	h.subBucketMask = (h.subBucketCount - 1) << h.unitMagnitude
+
+	// Find the number of buckets needed
+	// to cover the highest value.
+	smallestUntrackable := h.subBucketCount << h.unitMagnitude
+	buckets := 1
+	for smallestUntrackable <= highest {
+		if smallestUntrackable > math.MaxInt64/2 {
+			buckets++
+			break
+		}
+		smallestUntrackable <<= 1
+		buckets++
+	}
+
+	return h, (buckets + 1) * int(h.subBucketHalfCount)
+}
+
+func (h *hdr) add(t time.Duration) {
+	v := int64(t)
+	switch {
+	case v < 0:
+		v = 0
+	case v > h.highest:
+		v = h.highest
+	}
+
+	h.counts[h.index(v)]++
+	h.total++
+
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// index returns the counts index for value v.
+func (h *hdr) index(v int64) int {
+	pow2Ceiling := int64(64 - bits.LeadingZeros64(uint64(v|h.subBucketMask)))
+	bucket := pow2Ceiling - int64(h.unitMagnitude) - int64(h.subBucketHalfCountMagnitude+1)
+	subBucket := v >> (uint(bucket) + h.unitMagnitude)
+
+	base := (bucket + 1) << h.subBucketHalfCountMagnitude
+	return int(base + subBucket - h.subBucketHalfCount)
+}
+
+// bounds returns the lowest value and the width
+// of the value range counted at counts index i.
+func (h *hdr) bounds(i int) (int64, int64) {
+	bucket := int64(i>>h.subBucketHalfCountMagnitude) - 1
+	subBucket := int64(i)&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= h.subBucketHalfCount
+		bucket = 0
+	}
+
+	low := subBucket << (uint(bucket) + h.unitMagnitude)
+	width := int64(1) << (uint(bucket) + h.unitMagnitude)
+
+	return low, width
+}
+
+func (h *hdr) reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.total = 0
+	h.min, h.max = math.MaxInt64, 0
+}
+
+func (h *hdr) clone() recorder {
+	c := *h
+	c.counts = make([]uint64, len(h.counts))
+	copy(c.counts, h.counts)
+
+	return &c
+}
+
+func (h *hdr) merge(o recorder) {
+	oh := o.(*hdr)
+	for i, n := range oh.counts {
+		h.counts[i] += n
+	}
+
+	h.total += oh.total
+	if oh.min < h.min {
+		h.min = oh.min
+	}
+	if oh.max > h.max {
+		h.max = oh.max
+	}
+}
+
+// encode writes the populated
+// counts as index/count pairs.
+func (h *hdr) encode(e *encoder) {
+	e.uvarint(h.total)
+	e.varint(h.min)
+	e.varint(h.max)
+	e.uvarint(uint64(len(h.counts)))
+
+	var n uint64
+	for _, c := range h.counts {
+		if c > 0 {
+			n++
+		}
+	}
+
+	e.uvarint(n)
+	for i, c := range h.counts {
+		if c > 0 {
+			e.uvarint(uint64(i))
+			e.uvarint(c)
+		}
+	}
+}
+
+func (h *hdr) decode(d *decoder) {
+	h.total = d.uvarint()
+	h.min = d.varint()
+	h.max = d.varint()
+	if d.uvarint() != uint64(len(h.counts)) {
+		d.fail()
+		return
+	}
+
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+
+	for n := d.length(); n > 0; n-- {
+		i := d.uvarint()
+		c := d.uvarint()
+		if i >= uint64(len(h.counts)) {
+			d.fail()
+			return
+		}
+		h.counts[i] = c
+	}
+}
+
+// summary returns the populated buckets, each
+// represented by the midpoint of its value range
+// bounded by the exact observed min and max, which
+// are reported as the summary's min and max.
+func (h *hdr) summary() *summary {
+	var times timeSlice
+	var weights []float64
+
+	for i, n := range h.counts {
+		if n == 0 {
+			continue
+		}
+
+		low, width := h.bounds(i)
+		v := low + width/2
+		if v < h.min {
+			v = h.min
+		}
+		if v > h.max {
+			v = h.max
+		}
+
+		times = append(times, time.Duration(v))
+		weights = append(weights, float64(n))
+	}
+
+	s := newSummary(times, weights, h.total)
+	s.samples = int(h.total)
+	if len(times) > 0 {
+		s.extremes = &[2]time.Duration{time.Duration(h.min), time.Duration(h.max)}
+	}
+
+	return s
+}
@@ -0,0 +1,129 @@
+package tachymeter_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+// within returns whether got is within
+// relative error e of expected.
+func within(got, expected time.Duration, e float64) bool {
+	return math.Abs(float64(got-expected)) <= float64(expected)*e
+}
+
+func TestHDR(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Mode:      tachymeter.ModeHDR,
+			HDRDigits: 3,
+			Shards:    shards,
+		})
+
+		// 1µs..100ms in 1µs steps.
+		for i := 1; i <= 100000; i++ {
+			ta.AddTime(time.Duration(i) * time.Microsecond)
+		}
+
+		metrics := ta.Calc()
+
+		if metrics.Count != 100000 {
+			t.Errorf("Expected 100000, got %d\n", metrics.Count)
+		}
+
+		if metrics.Samples != 100000 {
+			t.Errorf("Expected 100000, got %d\n", metrics.Samples)
+		}
+
+		if metrics.Time.Min != time.Microsecond {
+			t.Errorf("Expected 1µs, got %s\n", metrics.Time.Min)
+		}
+
+		if metrics.Time.Max != 100*time.Millisecond {
+			t.Errorf("Expected 100ms, got %s\n", metrics.Time.Max)
+		}
+
+		expected := map[string][2]time.Duration{
+			"p50":  {metrics.Time.P50, 50 * time.Millisecond},
+			"p75":  {metrics.Time.P75, 75 * time.Millisecond},
+			"p95":  {metrics.Time.P95, 95 * time.Millisecond},
+			"p99":  {metrics.Time.P99, 99 * time.Millisecond},
+			"p999": {metrics.Time.P999, 99900 * time.Microsecond},
+			"avg":  {metrics.Time.Avg, 50000500 * time.Nanosecond},
+		}
+
+		for k, v := range expected {
+			if !within(v[0], v[1], 0.001) {
+				t.Errorf("%s: expected %s, got %s\n", k, v[1], v[0])
+			}
+		}
+
+		var hcount uint64
+		for _, bin := range *metrics.Histogram {
+			for _, v := range bin {
+				hcount += v
+			}
+		}
+
+		if hcount != 100000 {
+			t.Errorf("Expected histogram count of 100000, got %d\n", hcount)
+		}
+
+		ta.Reset()
+
+		if ta.Calc().Count != 0 {
+			t.Error("Expected 0 after reset")
+		}
+	}
+}
+
+func TestHDRClamp(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Mode:       tachymeter.ModeHDR,
+		HDRHighest: time.Second,
+	})
+
+	ta.AddTime(time.Millisecond)
+	ta.AddTime(time.Minute)
+
+	metrics := ta.Calc()
+
+	if metrics.Time.Max != time.Second {
+		t.Errorf("Expected 1s, got %s\n", metrics.Time.Max)
+	}
+}
+
+func TestHDRSingleBucket(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeHDR})
+
+	ta.AddTime(time.Millisecond)
+	ta.AddTime(1000100 * time.Nanosecond)
+
+	metrics := ta.Calc()
+
+	if metrics.Time.Min != time.Millisecond {
+		t.Errorf("Expected 1ms, got %s\n", metrics.Time.Min)
+	}
+
+	if metrics.Time.Max != 1000100*time.Nanosecond {
+		t.Errorf("Expected 1.0001ms, got %s\n", metrics.Time.Max)
+	}
+
+	if metrics.Time.Range != 100*time.Nanosecond {
+		t.Errorf("Expected 100ns, got %s\n", metrics.Time.Range)
+	}
+}
+
+func BenchmarkAddTimeHDR(b *testing.B) {
+	b.StopTimer()
+
+	ta := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeHDR})
+	d := time.Millisecond
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		ta.AddTime(d)
+	}
+}
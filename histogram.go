@@ -0,0 +1,175 @@
+package tachymeter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// HistogramMode selects how
+// histogram bins are sized.
+type HistogramMode int
+
+// Histogram modes.
+const (
+	// HistogramLinear divides the range between the
+	// min and max event durations into HBins bins of
+	// equal width. This is the default.
+	HistogramLinear HistogramMode = iota
+	// HistogramLog divides the range between the min
+	// and max event durations into HBins bins of
+	// equal width on a log scale.
+	HistogramLog
+	// HistogramExponential bins events by upper
+	// bounds of HStart, HStart*HFactor,
+	// HStart*HFactor^2 and so on, for HBins bins.
+	HistogramExponential
+	// HistogramCustom bins events by
+	// the upper bounds in HBounds.
+	HistogramCustom
+)
+
+// String returns the name of the HistogramMode.
+func (h HistogramMode) String() string {
+	switch h {
+	case HistogramLinear:
+		return "linear"
+	case HistogramLog:
+		return "log"
+	case HistogramExponential:
+		return "exponential"
+	case HistogramCustom:
+		return "custom"
+	default:
+		return "unknown"
+	}
+}
+
+// Bin is a histogram bin counting the events
+// with durations from Low to High, inclusive.
+// Bounds are JSON encoded in nanoseconds.
+type Bin struct {
+	Low   time.Duration
+	High  time.Duration
+	Count uint64
+}
+
+// String returns the bin range formatted
+// as e.g. '1ms - 2ms', rounded to the
+// microsecond.
+func (b Bin) String() string {
+	res := time.Duration(1000)
+	return fmt.Sprintf("%s - %s", b.Low/res*res, b.High/res*res)
+}
+
+// Bins is an ordered list of histogram bins.
+type Bins []Bin
+
+// Histogram returns the bins as a *Histogram
+// keyed by formatted bin ranges, for display.
+func (b Bins) Histogram() *Histogram {
+	hgram := make(Histogram, len(b))
+	for i, bin := range b {
+		hgram[i] = map[string]uint64{bin.String(): bin.Count}
+	}
+
+	return &hgram
+}
+
+// histogram returns histogram bins of event
+// durations binned as specified in c, along with
+// the bin size if bins are of equal width.
+func (s *summary) histogram(b int, c *Config) (Bins, time.Duration) {
+	if b < 1 {
+		b = 1
+	}
+
+	switch c.HMode {
+	case HistogramLog:
+		return s.hgramBounds(s.min(), s.logBounds(b)), 0
+	case HistogramExponential:
+		return s.hgramBounds(0, expBounds(b, c.HStart, c.HFactor)), 0
+	case HistogramCustom:
+		bounds := append([]time.Duration(nil), c.HBounds...)
+		sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+		return s.hgramBounds(0, bounds), 0
+	default:
+		return s.hgram(b)
+	}
+}
+
+// hgramBounds returns histogram bins of event
+// durations with the inclusive upper bounds bounds,
+// the first of which starts at low. Bins following
+// a bin with the same bound are zero-width. Events
+// above the last bound are counted in an additional
+// bin extending to the max.
+func (s *summary) hgramBounds(low time.Duration, bounds []time.Duration) Bins {
+	counts := make([]float64, len(bounds)+1)
+
+	var j int
+	for i, v := range s.times {
+		for j < len(bounds) && v > bounds[j] {
+			j++
+		}
+		counts[j] += s.w(i)
+	}
+
+	bins := make(Bins, 0, len(bounds)+1)
+	for i, high := range bounds {
+		if low > high {
+			low = high
+		}
+		bins = append(bins, Bin{Low: low, High: high, Count: uint64(math.Round(counts[i]))})
+		low = high + time.Nanosecond
+	}
+
+	if n := counts[len(bounds)]; n > 0 {
+		bins = append(bins, Bin{Low: low, High: s.max(), Count: uint64(math.Round(n))})
+	}
+
+	return bins
+}
+
+// logBounds returns the upper bounds of b bins of
+// equal width on a log scale between the min and
+// max event durations.
+func (s *summary) logBounds(b int) []time.Duration {
+	// Durations of 0 can't be log scaled.
+	min := math.Max(float64(s.min()), 1)
+	max := float64(s.max())
+	if max <= min {
+		return []time.Duration{s.max()}
+	}
+
+	step := math.Log(max/min) / float64(b)
+	bounds := make([]time.Duration, b)
+	for i := range bounds[:b-1] {
+		bounds[i] = time.Duration(min * math.Exp(step*float64(i+1)))
+	}
+	bounds[b-1] = s.max()
+
+	return bounds
+}
+
+// expBounds returns b exponentially growing upper
+// bounds, starting at start and increasing by
+// factor. start defaults to 1ms and factor to 2.
+func expBounds(b int, start time.Duration, factor float64) []time.Duration {
+	if start <= 0 {
+		start = time.Millisecond
+	}
+	if factor <= 1 {
+		factor = 2
+	}
+
+	bounds := make([]time.Duration, b)
+	bound := float64(start)
+	for i := range bounds {
+		bounds[i] = time.Duration(bound)
+		bound *= factor
+	}
+
+	return bounds
+}
@@ -0,0 +1,238 @@
+package tachymeter_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+// bins returns the labels and counts
+// of h in order.
+func bins(h *tachymeter.Histogram) ([]string, []uint64) {
+	var labels []string
+	var counts []uint64
+	for _, bin := range *h {
+		for k, v := range bin {
+			labels = append(labels, k)
+			counts = append(counts, v)
+		}
+	}
+
+	return labels, counts
+}
+
+func TestHistogramCustom(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:    10,
+		HMode:   tachymeter.HistogramCustom,
+		HBounds: []time.Duration{10 * time.Millisecond, time.Millisecond, 5 * time.Millisecond, 50 * time.Millisecond},
+	})
+
+	ta.AddTime(500 * time.Microsecond)
+	for _, d := range []int{2, 3, 7, 20, 40, 100} {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+	labels, counts := bins(metrics.Histogram)
+
+	expectedLabels := []string{"0s - 1ms", "1ms - 5ms", "5ms - 10ms", "10ms - 50ms", "50ms - 100ms"}
+	if !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("Expected %v, got %v\n", expectedLabels, labels)
+	}
+
+	expectedCounts := []uint64{1, 2, 1, 2, 1}
+	if !reflect.DeepEqual(counts, expectedCounts) {
+		t.Errorf("Expected %v, got %v\n", expectedCounts, counts)
+	}
+
+	if metrics.HistogramBinSize != 0 {
+		t.Errorf("Expected 0, got %s\n", metrics.HistogramBinSize)
+	}
+}
+
+func TestHistogramExponential(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:    10,
+		HBins:   3,
+		HMode:   tachymeter.HistogramExponential,
+		HStart:  time.Millisecond,
+		HFactor: 10,
+	})
+
+	for _, d := range []int{2, 3, 7, 20, 40, 100} {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	labels, counts := bins(ta.Calc().Histogram)
+
+	expectedLabels := []string{"0s - 1ms", "1ms - 10ms", "10ms - 100ms"}
+	if !reflect.DeepEqual(labels, expectedLabels) {
+		t.Errorf("Expected %v, got %v\n", expectedLabels, labels)
+	}
+
+	expectedCounts := []uint64{0, 3, 3}
+	if !reflect.DeepEqual(counts, expectedCounts) {
+		t.Errorf("Expected %v, got %v\n", expectedCounts, counts)
+	}
+}
+
+func TestHistogramLog(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:  10,
+		HBins: 3,
+		HMode: tachymeter.HistogramLog,
+	})
+
+	for _, d := range []int{1, 2, 20, 200, 1000} {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	labels, counts := bins(ta.Calc().Histogram)
+
+	if len(labels) != 3 || labels[0][:6] != "1ms - " || labels[2][len(labels[2])-5:] != " - 1s" {
+		t.Errorf("Unexpected bins %v\n", labels)
+	}
+
+	expectedCounts := []uint64{2, 1, 2}
+	if !reflect.DeepEqual(counts, expectedCounts) {
+		t.Errorf("Expected %v, got %v\n", expectedCounts, counts)
+	}
+}
+
+func TestHistogramBins(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:    10,
+		HMode:   tachymeter.HistogramCustom,
+		HBounds: []time.Duration{time.Millisecond, 5 * time.Millisecond},
+	})
+
+	for _, d := range []time.Duration{500 * time.Microsecond, 2 * time.Millisecond, 8 * time.Millisecond} {
+		ta.AddTime(d)
+	}
+
+	metrics := ta.Calc()
+
+	expected := tachymeter.Bins{
+		{Low: 0, High: time.Millisecond, Count: 1},
+		{Low: time.Millisecond + 1, High: 5 * time.Millisecond, Count: 1},
+		{Low: 5*time.Millisecond + 1, High: 8 * time.Millisecond, Count: 1},
+	}
+
+	if !reflect.DeepEqual(metrics.Bins, expected) {
+		t.Errorf("Expected %v, got %v\n", expected, metrics.Bins)
+	}
+
+	if !reflect.DeepEqual(metrics.Histogram, metrics.Bins.Histogram()) {
+		t.Errorf("Expected %v, got %v\n", metrics.Bins.Histogram(), metrics.Histogram)
+	}
+
+	if !strings.Contains(metrics.JSON(), `"Bins":[{"Low":0,"High":1000000,"Count":1},{"Low":1000001,"High":5000000,"Count":1}`) {
+		t.Errorf("Expected numeric bins in JSON, got %s\n", metrics.JSON())
+	}
+}
+
+func TestHistogramLinear(t *testing.T) {
+	ms := time.Millisecond
+
+	tests := []struct {
+		name   string
+		hBins  int
+		times  []time.Duration
+		bounds []time.Duration // Upper bounds.
+		counts []uint64
+	}{
+		{
+			name:   "empty ranges",
+			hBins:  9,
+			times:  []time.Duration{10 * ms, 11 * ms, 50 * ms, 100 * ms},
+			bounds: []time.Duration{20 * ms, 30 * ms, 40 * ms, 50 * ms, 60 * ms, 70 * ms, 80 * ms, 90 * ms, 100 * ms},
+			counts: []uint64{2, 0, 0, 1, 0, 0, 0, 0, 1},
+		},
+		{
+			name:   "uneven interval",
+			hBins:  4,
+			times:  []time.Duration{1 * ms, 2 * ms, 3 * ms, 4 * ms, 5 * ms, 6 * ms, 7 * ms, 8 * ms, 9 * ms, 10 * ms},
+			bounds: []time.Duration{3250 * time.Microsecond, 5500 * time.Microsecond, 7750 * time.Microsecond, 10 * ms},
+			counts: []uint64{3, 2, 2, 3},
+		},
+		{
+			name:   "inclusive upper bounds",
+			hBins:  2,
+			times:  []time.Duration{0, 5 * ms, 5 * ms, 6 * ms, 10 * ms},
+			bounds: []time.Duration{5 * ms, 10 * ms},
+			counts: []uint64{3, 2},
+		},
+		{
+			name:   "no range",
+			hBins:  3,
+			times:  []time.Duration{5 * ms, 5 * ms},
+			bounds: []time.Duration{5 * ms, 5 * ms, 5 * ms},
+			counts: []uint64{2, 0, 0},
+		},
+		{
+			name:   "range below bins",
+			hBins:  4,
+			times:  []time.Duration{5, 6, 8},
+			bounds: []time.Duration{5, 5, 5, 8},
+			counts: []uint64{1, 0, 0, 2},
+		},
+		{
+			name:   "single bin",
+			hBins:  1,
+			times:  []time.Duration{2 * ms, 4 * ms},
+			bounds: []time.Duration{4 * ms},
+			counts: []uint64{2},
+		},
+	}
+
+	for _, test := range tests {
+		ta := tachymeter.New(&tachymeter.Config{Size: len(test.times), HBins: test.hBins})
+		for _, d := range test.times {
+			ta.AddTime(d)
+		}
+
+		metrics := ta.Calc()
+
+		if len(metrics.Bins) != test.hBins || len(*metrics.Histogram) != test.hBins {
+			t.Errorf("%s: Expected %d bins, got %d\n", test.name, test.hBins, len(metrics.Bins))
+			continue
+		}
+
+		low := metrics.Time.Min
+		for i, bin := range metrics.Bins {
+			// Bins with the same bound as the
+			// previous bin are zero-width.
+			if low > test.bounds[i] {
+				low = test.bounds[i]
+			}
+			if bin.Low != low || bin.High != test.bounds[i] || bin.Count != test.counts[i] {
+				t.Errorf("%s: Expected bin %d of %s - %s: %d, got %s - %s: %d\n",
+					test.name, i, low, test.bounds[i], test.counts[i], bin.Low, bin.High, bin.Count)
+			}
+			if bin.Low > bin.High {
+				t.Errorf("%s: Expected bin %d low %s <= high %s\n", test.name, i, bin.Low, bin.High)
+			}
+			low = bin.High + time.Nanosecond
+		}
+	}
+}
+
+func TestHistogramNegativeBins(t *testing.T) {
+	for _, mode := range []tachymeter.HistogramMode{
+		tachymeter.HistogramLinear,
+		tachymeter.HistogramLog,
+		tachymeter.HistogramExponential,
+	} {
+		ta := tachymeter.New(&tachymeter.Config{Size: 10, HBins: -1, HMode: mode})
+		ta.AddTime(time.Millisecond)
+		ta.AddTime(2 * time.Millisecond)
+
+		if metrics := ta.Calc(); len(metrics.Bins) < 1 {
+			t.Errorf("%s: Expected at least 1 bin, got %d\n", mode, len(metrics.Bins))
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package tachymeter
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Merge returns a *Metrics summarizing the union
+// of the events held by each Tachymeter in ts.
+// Tachymeters of the same Mode and parameters are
+// merged losslessly; sketch modes are merged as
+// sketches. Otherwise, the events held by each are
+// combined as samples. The histogram bin count and
+// Mode of the first Tachymeter are used. ts are
+// assumed to have run concurrently: if any has a
+// wall time set, the longest is used for rate
+// outputs.
+func Merge(ts ...*Tachymeter) *Metrics {
+	if len(ts) == 0 {
+		return newSummary(nil, nil, 0).calc(0, 0, &Config{})
+	}
+
+	var recs []recorder
+	var wallTime time.Duration
+
+	for _, t := range ts {
+		t.Lock()
+		recs = append(recs, t.collect())
+		if t.WallTime > wallTime {
+			wallTime = t.WallTime
+		}
+		t.Unlock()
+	}
+
+	var s *summary
+	if mergeable(recs...) {
+		r := recs[0]
+		for _, o := range recs[1:] {
+			r.merge(o)
+		}
+		s = r.summary()
+	} else {
+		summaries := make([]*summary, len(recs))
+		for i, r := range recs {
+			summaries[i] = r.summary()
+		}
+		s = mergeSummaries(summaries...)
+	}
+
+	metrics := s.calc(wallTime, ts[0].HBins, &ts[0].config)
+	metrics.Mode = ts[0].config.Mode
+	for _, t := range ts {
+		if atomic.LoadUint32(&t.corrected) == 1 {
+			metrics.Corrected = true
+		}
+	}
+
+	// Merge the outcome Tachymeters
+	// of those that have them.
+	var success, failure []*Tachymeter
+	for _, t := range ts {
+		if o := t.getOutcomes(); o != nil {
+			success = append(success, o.success)
+			failure = append(failure, o.failure)
+		}
+	}
+
+	if success != nil {
+		metrics.setOutcomes(Merge(success...), Merge(failure...))
+	}
+
+	return metrics
+}
+
+// mergeable returns whether recs are of the
+// same type and have compatible parameters.
+func mergeable(recs ...recorder) bool {
+	for _, r := range recs[1:] {
+		if reflect.TypeOf(r) != reflect.TypeOf(recs[0]) {
+			return false
+		}
+
+		switch a := recs[0].(type) {
+		case *hdr:
+			b := r.(*hdr)
+			if len(a.counts) != len(b.counts) ||
+				a.unitMagnitude != b.unitMagnitude ||
+				a.subBucketHalfCountMagnitude != b.subBucketHalfCountMagnitude {
+				return false
+			}
+		case *DDSketch:
+			if a.alpha != r.(*DDSketch).alpha {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// mergeSummaries returns a *summary of the
+// events represented by each of ss.
+func mergeSummaries(ss ...*summary) *summary {
+	var times timeSlice
+	var weights []float64
+	var count uint64
+	var samples int
+
+	// The min and max are carried over from
+	// the summaries, which may track them
+	// more precisely than their times.
+	var extremes *[2]time.Duration
+
+	for _, s := range ss {
+		if len(s.times) > 0 {
+			min, max := s.min(), s.max()
+			if extremes == nil {
+				extremes = &[2]time.Duration{min, max}
+			}
+			if min < extremes[0] {
+				extremes[0] = min
+			}
+			if max > extremes[1] {
+				extremes[1] = max
+			}
+		}
+
+		times = append(times, s.times...)
+		for i := range s.times {
+			weights = append(weights, s.w(i))
+		}
+		count += s.count
+		samples += s.samples
+	}
+
+	s := newSummary(times, weights, count)
+	s.samples = samples
+	s.extremes = extremes
+
+	return s
+}
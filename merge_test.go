@@ -0,0 +1,132 @@
+package tachymeter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestMerge(t *testing.T) {
+	a := tachymeter.New(&tachymeter.Config{Size: 10})
+	b := tachymeter.New(&tachymeter.Config{Size: 5})
+
+	for i := 1; i <= 12; i++ {
+		a.AddTime(time.Duration(i) * time.Millisecond)
+	}
+
+	for i := 1; i <= 5; i++ {
+		b.AddTime(time.Duration(i*100) * time.Millisecond)
+	}
+
+	a.SetWallTime(time.Second)
+	b.SetWallTime(2 * time.Second)
+
+	metrics := tachymeter.Merge(a, b)
+
+	if metrics.Count != 17 {
+		t.Errorf("Expected 17, got %d\n", metrics.Count)
+	}
+
+	if metrics.Samples != 15 {
+		t.Errorf("Expected 15, got %d\n", metrics.Samples)
+	}
+
+	// a's window holds 3ms..12ms.
+	if metrics.Time.Min != 3*time.Millisecond {
+		t.Errorf("Expected 3ms, got %s\n", metrics.Time.Min)
+	}
+
+	if metrics.Time.Max != 500*time.Millisecond {
+		t.Errorf("Expected 500ms, got %s\n", metrics.Time.Max)
+	}
+
+	if metrics.Time.Cumulative != 1575*time.Millisecond {
+		t.Errorf("Expected 1.575s, got %s\n", metrics.Time.Cumulative)
+	}
+
+	// The longest wall time is used.
+	if metrics.Rate.Second != 8.5 {
+		t.Errorf("Expected 8.5, got %f\n", metrics.Rate.Second)
+	}
+
+	// The merge doesn't modify the sources.
+	if a.Calc().Count != 12 || b.Calc().Count != 5 {
+		t.Error("Expected sources to be unmodified")
+	}
+}
+
+func TestMergeSketches(t *testing.T) {
+	for _, mode := range []tachymeter.Mode{tachymeter.ModeHDR, tachymeter.ModeTDigest, tachymeter.ModeDDSketch} {
+		c := &tachymeter.Config{Mode: mode}
+		a, b := tachymeter.New(c), tachymeter.New(c)
+
+		for i := 1; i <= 1000; i++ {
+			a.AddTime(time.Duration(i) * time.Millisecond)
+			b.AddTime(time.Duration(i+1000) * time.Millisecond)
+		}
+
+		metrics := tachymeter.Merge(a, b)
+
+		if metrics.Count != 2000 {
+			t.Errorf("%s: expected 2000, got %d\n", mode, metrics.Count)
+		}
+
+		if metrics.Time.Min != time.Millisecond {
+			t.Errorf("%s: expected 1ms, got %s\n", mode, metrics.Time.Min)
+		}
+
+		if metrics.Time.Max != 2*time.Second {
+			t.Errorf("%s: expected 2s, got %s\n", mode, metrics.Time.Max)
+		}
+
+		if !within(metrics.Time.P50, time.Second, 0.01) {
+			t.Errorf("%s: expected ~1s, got %s\n", mode, metrics.Time.P50)
+		}
+
+		if !within(metrics.Time.P99, 1980*time.Millisecond, 0.01) {
+			t.Errorf("%s: expected ~1.98s, got %s\n", mode, metrics.Time.P99)
+		}
+	}
+}
+
+func TestMergeMixed(t *testing.T) {
+	a := tachymeter.New(&tachymeter.Config{Size: 100})
+	b := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeHDR})
+
+	for i := 0; i < 100; i++ {
+		a.AddTimeResult(time.Millisecond, nil)
+		b.AddTimeResult(3*time.Millisecond, errors.New("failed"))
+	}
+
+	metrics := tachymeter.Merge(a, b)
+
+	if metrics.Count != 200 {
+		t.Errorf("Expected 200, got %d\n", metrics.Count)
+	}
+
+	if metrics.Time.Avg != 2*time.Millisecond {
+		t.Errorf("Expected 2ms, got %s\n", metrics.Time.Avg)
+	}
+
+	if metrics.Errors != 100 || metrics.ErrorRate != 0.5 {
+		t.Errorf("Expected 100 errors, got %d\n", metrics.Errors)
+	}
+
+	if metrics.Failure.Time.Min != 3*time.Millisecond {
+		t.Errorf("Expected 3ms, got %s\n", metrics.Failure.Time.Min)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	metrics := tachymeter.Merge()
+
+	if metrics.Count != 0 {
+		t.Errorf("Expected 0, got %d\n", metrics.Count)
+	}
+
+	if cdf := metrics.Distribution.CDF(time.Millisecond); cdf != 0 {
+		t.Errorf("Expected 0, got %f\n", cdf)
+	}
+}
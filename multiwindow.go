@@ -0,0 +1,82 @@
+package tachymeter
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// MultiWindow summarizes a single stream of
+// events over several trailing horizons, such
+// as the last 1, 5 and 15 minutes.
+type MultiWindow struct {
+	*Tachymeter
+	horizons []time.Duration
+}
+
+// NewMultiWindow initializes a *MultiWindow that
+// summarizes events over each of the horizons
+// specified, defaulting to 1m, 5m and 15m. The
+// Mode and Window fields of c are ignored; all
+// other fields apply to each horizon.
+func NewMultiWindow(c *Config, horizons ...time.Duration) *MultiWindow {
+	if len(horizons) == 0 {
+		horizons = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+	}
+
+	h := append([]time.Duration(nil), horizons...)
+	sort.Slice(h, func(i, j int) bool { return h[i] < h[j] })
+
+	// Events are held for the longest
+	// of the horizons.
+	tc := *c
+	tc.Mode = ModeTimeWindow
+	tc.Window = h[len(h)-1]
+
+	return &MultiWindow{
+		Tachymeter: New(&tc),
+		horizons:   h,
+	}
+}
+
+// CalcWindows returns a *Metrics summarizing
+// the events observed within each horizon,
+// keyed by horizon. Outcomes of events added
+// with AddTimeResult are summarized within the
+// same horizons.
+func (w *MultiWindow) CalcWindows() map[time.Duration]*Metrics {
+	now := time.Now()
+	metrics := calcWindows(w.Tachymeter, now, w.horizons)
+
+	if o := w.getOutcomes(); o != nil {
+		success := calcWindows(o.success, now, w.horizons)
+		failure := calcWindows(o.failure, now, w.horizons)
+		for h, m := range metrics {
+			m.setOutcomes(success[h], failure[h])
+		}
+	}
+
+	return metrics
+}
+
+// calcWindows returns a *Metrics summarizing the
+// events held by the ModeTimeWindow Tachymeter t
+// within each horizon as of now, keyed by horizon.
+func calcWindows(t *Tachymeter, now time.Time, horizons []time.Duration) map[time.Duration]*Metrics {
+	t.Lock()
+	r := t.collect().(*timeWindow)
+	wallTime := t.WallTime
+	t.Unlock()
+
+	corrected := atomic.LoadUint32(&t.corrected) == 1
+	metrics := make(map[time.Duration]*Metrics, len(horizons))
+
+	for _, h := range horizons {
+		m := r.summaryWithin(now, h).calc(wallTime, t.HBins, &t.config)
+		m.Mode = t.config.Mode
+		m.Corrected = corrected
+		metrics[h] = m
+	}
+
+	return metrics
+}
@@ -0,0 +1,95 @@
+package tachymeter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestMultiWindow(t *testing.T) {
+	short, long := 150*time.Millisecond, time.Minute
+	mw := tachymeter.NewMultiWindow(&tachymeter.Config{Shards: 2}, long, short)
+
+	for i := 0; i < 100; i++ {
+		mw.AddTime(time.Second)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	for i := 0; i < 50; i++ {
+		mw.AddTime(time.Millisecond)
+	}
+
+	windows := mw.CalcWindows()
+
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2, got %d\n", len(windows))
+	}
+
+	if c := windows[short].Count; c != 50 {
+		t.Errorf("Expected 50, got %d\n", c)
+	}
+
+	if p := windows[short].Time.P99; p != time.Millisecond {
+		t.Errorf("Expected 1ms, got %s\n", p)
+	}
+
+	if c := windows[long].Count; c != 150 {
+		t.Errorf("Expected 150, got %d\n", c)
+	}
+
+	if p := windows[long].Time.P99; p != time.Second {
+		t.Errorf("Expected 1s, got %s\n", p)
+	}
+
+	// Calc summarizes the longest horizon.
+	if c := mw.Calc().Count; c != 150 {
+		t.Errorf("Expected 150, got %d\n", c)
+	}
+}
+
+func TestMultiWindowDefaults(t *testing.T) {
+	mw := tachymeter.NewMultiWindow(&tachymeter.Config{})
+	mw.AddTime(time.Millisecond)
+
+	windows := mw.CalcWindows()
+
+	for _, h := range []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute} {
+		m, ok := windows[h]
+		if !ok {
+			t.Errorf("Expected a %s window\n", h)
+			continue
+		}
+
+		if m.Count != 1 {
+			t.Errorf("Expected 1, got %d\n", m.Count)
+		}
+	}
+}
+
+func TestMultiWindowOutcomes(t *testing.T) {
+	short, long := 150*time.Millisecond, time.Minute
+	mw := tachymeter.NewMultiWindow(&tachymeter.Config{}, short, long)
+
+	for i := 0; i < 10; i++ {
+		mw.AddTimeResult(time.Second, errors.New("failed"))
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	for i := 0; i < 30; i++ {
+		mw.AddTimeResult(time.Millisecond, nil)
+	}
+
+	windows := mw.CalcWindows()
+
+	if m := windows[short]; m.Errors != 0 || m.Success.Count != 30 {
+		t.Errorf("Expected 0 errors and 30 successes, got %d and %d\n", m.Errors, m.Success.Count)
+	}
+
+	if m := windows[long]; m.Errors != 10 || m.ErrorRate != 0.25 {
+		t.Errorf("Expected 10 errors at 0.25, got %d at %f\n", m.Errors, m.ErrorRate)
+	}
+}
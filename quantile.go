@@ -0,0 +1,143 @@
+package tachymeter
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// QuantileMethod selects how percentiles are
+// calculated from the recorded events.
+type QuantileMethod int
+
+// Quantile methods. QuantileR1 through QuantileR9 are
+// the sample quantile definitions from Hyndman and Fan,
+// "Sample Quantiles in Statistical Packages" (1996),
+// numbered as in R's quantile function.
+const (
+	// QuantileRounded selects the event at the rounded
+	// rank n*p, and the event at rank n/2+1 for p50.
+	QuantileRounded QuantileMethod = iota
+	// Inverse of the empirical CDF.
+	QuantileR1
+	// Inverse of the empirical CDF, averaged
+	// at discontinuities.
+	QuantileR2
+	// Observation closest to n*p, ties to even.
+	QuantileR3
+	// Linear interpolation of the empirical CDF.
+	QuantileR4
+	// Piecewise linear, with knots midway
+	// through the steps of the empirical CDF.
+	QuantileR5
+	// Linear interpolation of the expectations
+	// of the order statistics (Weibull).
+	QuantileR6
+	// Linear interpolation of the modes of the
+	// order statistics.
+	QuantileR7
+	// Approximately median-unbiased.
+	QuantileR8
+	// Approximately unbiased for normally
+	// distributed events.
+	QuantileR9
+)
+
+// Aliases for commonly referenced methods.
+const (
+	// QuantileNearestRank selects the event at
+	// rank ceil(n*p).
+	QuantileNearestRank = QuantileR1
+	// QuantileLinear is the default of numpy,
+	// R and Excel's PERCENTILE.INC.
+	QuantileLinear = QuantileR7
+)
+
+// String returns the name of the QuantileMethod.
+func (q QuantileMethod) String() string {
+	switch {
+	case q == QuantileRounded:
+		return "rounded"
+	case q >= QuantileR1 && q <= QuantileR9:
+		return fmt.Sprintf("r%d", int(q))
+	default:
+		return "unknown"
+	}
+}
+
+// hfParams are the Hyndman-Fan plotting
+// position parameters a and b of the
+// continuous methods R4 through R9.
+var hfParams = map[QuantileMethod][2]float64{
+	QuantileR4: {0, 1},
+	QuantileR5: {0.5, 0.5},
+	QuantileR6: {0, 0},
+	QuantileR7: {1, 1},
+	QuantileR8: {1.0 / 3, 1.0 / 3},
+	QuantileR9: {3.0 / 8, 3.0 / 8},
+}
+
+// hfQuantile returns the duration at quantile q
+// using the Hyndman-Fan method specified in
+// s.method. Weighted events count as that many
+// events of the same duration.
+func (s *summary) hfQuantile(q float64) time.Duration {
+	var m, h float64
+
+	switch s.method {
+	case QuantileR1, QuantileR2, QuantileR3:
+		m = s.n * q
+		if s.method == QuantileR3 {
+			m -= 0.5
+		}
+
+		j := math.Floor(m + quantileFuzz(m))
+		switch {
+		case s.method == QuantileR2 && m > j:
+			h = 1
+		case s.method == QuantileR2:
+			h = 0.5
+		case m > j:
+			h = 1
+		case s.method == QuantileR3 && math.Mod(j, 2) != 0:
+			h = 1
+		}
+
+		return s.interpolate(j, h)
+	default:
+		ab, ok := hfParams[s.method]
+		if !ok {
+			return s.rank(math.Floor(s.n*q + 0.5))
+		}
+
+		m = ab[0] + q*(s.n+1-ab[0]-ab[1])
+		j := math.Floor(m + quantileFuzz(m))
+		if h = m - j; math.Abs(h) < quantileFuzz(m) {
+			h = 0
+		}
+
+		return s.interpolate(j, h)
+	}
+}
+
+// quantileFuzz returns the tolerance used
+// to absorb floating point error in the
+// rank m when compared to an integer.
+func quantileFuzz(m float64) float64 {
+	return 4 * 2.220446049250313e-16 * math.Max(1, math.Abs(m))
+}
+
+// interpolate returns the duration h of the
+// way between the events ranked j and j+1.
+// Ranks beyond either end are clamped to
+// the min and max.
+func (s *summary) interpolate(j, h float64) time.Duration {
+	lo := s.rank(j)
+	if h == 0 {
+		return lo
+	}
+
+	hi := s.rank(j + 1)
+
+	return lo + time.Duration(h*float64(hi-lo))
+}
@@ -0,0 +1,86 @@
+package tachymeter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestQuantileMethods(t *testing.T) {
+	ms := func(f float64) time.Duration {
+		return time.Duration(f * float64(time.Millisecond))
+	}
+
+	// Reference values from R's
+	// quantile(x, c(0.1, 0.25, 0.5, 0.9), type = n).
+	expected := map[tachymeter.QuantileMethod][4]time.Duration{
+		tachymeter.QuantileR1: {ms(1), ms(3), ms(7), ms(30)},
+		tachymeter.QuantileR2: {ms(1), ms(3.5), ms(8.5), ms(30)},
+		tachymeter.QuantileR3: {ms(1), ms(3), ms(7), ms(20)},
+		tachymeter.QuantileR4: {ms(1), ms(3), ms(7), ms(22)},
+		tachymeter.QuantileR5: {ms(1.6), ms(3.5), ms(8.5), ms(27)},
+		tachymeter.QuantileR6: {ms(1), ms(3.25), ms(8.5), ms(30)},
+		tachymeter.QuantileR7: {ms(2.4), ms(3.75), ms(8.5), ms(23)},
+		tachymeter.QuantileR8: {ms(1.333333), ms(3.416667), ms(8.5), ms(28.333333)},
+		tachymeter.QuantileR9: {ms(1.4), ms(3.4375), ms(8.5), ms(28)},
+	}
+
+	for method, e := range expected {
+		ta := tachymeter.New(&tachymeter.Config{
+			Size:        8,
+			Quantile:    method,
+			Percentiles: []float64{10, 25, 90},
+		})
+
+		for _, d := range []float64{20, 1, 14, 3, 30, 7, 4, 10} {
+			ta.AddTime(ms(d))
+		}
+
+		metrics := ta.Calc()
+		got := [4]time.Duration{
+			metrics.Percentiles[0].Value,
+			metrics.Percentiles[1].Value,
+			metrics.Time.P50,
+			metrics.Percentiles[2].Value,
+		}
+
+		for i := range got {
+			if !within(got[i], e[i], 1e-6) {
+				t.Errorf("%s: Expected %s, got %s\n", method, e[i], got[i])
+			}
+		}
+	}
+}
+
+func TestQuantileLinearWeighted(t *testing.T) {
+	// Merged reservoirs hold weighted samples;
+	// these should interpolate as if each weight
+	// were that many events.
+	a := tachymeter.New(&tachymeter.Config{
+		Size:     10,
+		Mode:     tachymeter.ModeReservoir,
+		Quantile: tachymeter.QuantileLinear,
+	})
+	b := tachymeter.New(&tachymeter.Config{
+		Size:     10,
+		Mode:     tachymeter.ModeReservoir,
+		Quantile: tachymeter.QuantileLinear,
+	})
+
+	for i := 1; i <= 10; i++ {
+		a.AddTime(time.Duration(i) * time.Millisecond)
+		b.AddTime(time.Duration(i) * time.Millisecond)
+	}
+
+	metrics := tachymeter.Merge(a, b)
+
+	// R7 over 1, 1, 2, 2, ... 10, 10.
+	if metrics.Time.P50 != 5500*time.Microsecond {
+		t.Errorf("Expected 5.5ms, got %s\n", metrics.Time.P50)
+	}
+
+	if metrics.Time.P75 != 8*time.Millisecond {
+		t.Errorf("Expected 8ms, got %s\n", metrics.Time.P75)
+	}
+}
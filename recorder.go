@@ -0,0 +1,137 @@
+package tachymeter
+
+import (
+	"time"
+)
+
+// recorder is a Tachymeter storage backend.
+// Recorders aren't thread safe; callers
+// serialize access through a shard lock.
+type recorder interface {
+	// add records an event duration.
+	add(t time.Duration)
+	// reset discards all recorded events.
+	reset()
+	// clone returns a deep copy of the recorder.
+	clone() recorder
+	// merge folds the events held by o, which
+	// must be of the same type, into the recorder.
+	merge(o recorder)
+	// summary returns the recorded events
+	// in a form that Calc can consume.
+	summary() *summary
+	// encode writes the recorder's state to e.
+	encode(e *encoder)
+	// decode restores state written by encode
+	// into a recorder created with the same
+	// parameters.
+	decode(d *decoder)
+}
+
+// newRecorder returns a recorder for the mode
+// specified in c. n is the number of shards the
+// recorder's capacity is divided among.
+func newRecorder(c *Config, n int) recorder {
+	switch c.Mode {
+	case ModeHDR:
+		return newHDR(c)
+	case ModeTDigest:
+		return newTDigest(c)
+	case ModeDDSketch:
+		return newDDSketch(c.RelativeAccuracy)
+	case ModeReservoir:
+		return newReservoir(shardSize(c.Size, n))
+	case ModeDecay:
+		return newDecay(c, shardSize(c.Size, n))
+	case ModeTimeWindow:
+		return newTimeWindow(c, n)
+	default:
+		return &ring{times: make(timeSlice, shardSize(c.Size, n))}
+	}
+}
+
+// shardSize returns an equal portion of the
+// sample capacity size for each of n shards,
+// rounded up.
+func shardSize(size, n int) int {
+	s := (size + n - 1) / n
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
+// ring is a lossless sliding window of the
+// last len(times) event durations.
+type ring struct {
+	times timeSlice
+	count uint64
+}
+
+func (r *ring) add(t time.Duration) {
+	r.times[r.count%uint64(len(r.times))] = t
+	r.count++
+}
+
+func (r *ring) reset() {
+	r.count = 0
+}
+
+// window returns the populated portion of
+// the ring.
+func (r *ring) window() timeSlice {
+	if r.count < uint64(len(r.times)) {
+		return r.times[:r.count]
+	}
+	return r.times
+}
+
+func (r *ring) clone() recorder {
+	c := &ring{
+		times: make(timeSlice, len(r.times)),
+		count: r.count,
+	}
+	copy(c.times, r.times)
+
+	return c
+}
+
+// merge appends the window of o to the window
+// of r. The merged ring holds the union of both
+// sample sets and isn't intended for further
+// writes.
+func (r *ring) merge(o recorder) {
+	or := o.(*ring)
+	times := make(timeSlice, 0, len(r.window())+len(or.window()))
+	times = append(times, r.window()...)
+	times = append(times, or.window()...)
+
+	r.times = times
+	r.count += or.count
+}
+
+func (r *ring) encode(e *encoder) {
+	e.uvarint(r.count)
+	e.uvarint(uint64(len(r.times)))
+	for _, t := range r.times {
+		e.duration(t)
+	}
+}
+
+func (r *ring) decode(d *decoder) {
+	r.count = d.uvarint()
+	if d.length() != len(r.times) {
+		d.fail()
+		return
+	}
+	for i := range r.times {
+		r.times[i] = d.duration()
+	}
+}
+
+func (r *ring) summary() *summary {
+	times := make(timeSlice, len(r.window()))
+	copy(times, r.window())
+
+	return newSummary(times, nil, r.count)
+}
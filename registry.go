@@ -0,0 +1,171 @@
+package tachymeter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds Tachymeters keyed by name and
+// label set, creating them on first use.
+type Registry struct {
+	sync.RWMutex
+	config  Config
+	entries map[string]*registryEntry
+}
+
+// registryEntry is a named, labeled
+// Tachymeter held by a Registry.
+type registryEntry struct {
+	name   string
+	labels []string // Key/value pairs, sorted by key.
+	t      *Tachymeter
+}
+
+// NewRegistry initializes a new *Registry. Each
+// Tachymeter it creates is initialized with c.
+func NewRegistry(c *Config) *Registry {
+	return &Registry{
+		config:  *c,
+		entries: map[string]*registryEntry{},
+	}
+}
+
+// sortLabels returns labels as key/value pairs
+// sorted by key. A trailing key without a value
+// is given an empty value.
+func sortLabels(labels []string) []string {
+	if len(labels)%2 != 0 {
+		// Copied so that padding doesn't write
+		// into the caller's backing array.
+		labels = append(labels[:len(labels):len(labels)], "")
+	}
+
+	pairs := make([][2]string, len(labels)/2)
+	for i := range pairs {
+		pairs[i] = [2]string{labels[2*i], labels[2*i+1]}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	sorted := make([]string, 0, len(labels))
+	for _, p := range pairs {
+		sorted = append(sorted, p[0], p[1])
+	}
+
+	return sorted
+}
+
+// Key returns the key that identifies name and
+// labels, which are key/value pairs, in the form
+// 'name{k1=v1,k2=v2}', or 'name' without labels.
+// Labels are sorted by key. The characters '{',
+// '}', ',', '=' and '\' within name and labels
+// are escaped with a '\'.
+func Key(name string, labels ...string) string {
+	return key(name, sortLabels(labels))
+}
+
+// keyEscaper escapes the characters that
+// delimit names and labels in a key.
+var keyEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`,`, `\,`,
+	`=`, `\=`,
+)
+
+// key returns the key for name
+// and sorted labels.
+func key(name string, labels []string) string {
+	if len(labels) == 0 {
+		return keyEscaper.Replace(name)
+	}
+
+	var b strings.Builder
+	b.WriteString(keyEscaper.Replace(name))
+	b.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(keyEscaper.Replace(labels[i]))
+		b.WriteByte('=')
+		b.WriteString(keyEscaper.Replace(labels[i+1]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// Get returns the Tachymeter for name and labels,
+// which are key/value pairs, creating it if it
+// doesn't exist. The order of label pairs isn't
+// significant.
+//
+//	reg.Get("db.query", "table", "users").AddTime(d)
+func (r *Registry) Get(name string, labels ...string) *Tachymeter {
+	sorted := sortLabels(labels)
+	k := key(name, sorted)
+
+	r.RLock()
+	e, ok := r.entries[k]
+	r.RUnlock()
+
+	if ok {
+		return e.t
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// Check again in case it was
+	// created since the read lock.
+	if e, ok := r.entries[k]; ok {
+		return e.t
+	}
+
+	e = &registryEntry{
+		name:   name,
+		labels: sorted,
+		t:      New(&r.config),
+	}
+	r.entries[k] = e
+
+	return e.t
+}
+
+// Each calls f for each Tachymeter held by the
+// Registry in key order. labels are key/value
+// pairs sorted by key.
+func (r *Registry) Each(f func(name string, labels []string, t *Tachymeter)) {
+	r.RLock()
+	keys := make([]string, 0, len(r.entries))
+	for k := range r.entries {
+		keys = append(keys, k)
+	}
+
+	entries := make([]*registryEntry, len(keys))
+	sort.Strings(keys)
+	for i, k := range keys {
+		entries[i] = r.entries[k]
+	}
+	r.RUnlock()
+
+	for _, e := range entries {
+		f(e.name, e.labels, e.t)
+	}
+}
+
+// CalcAll calls Calc on each Tachymeter held by
+// the Registry, returning the *Metrics keyed by
+// name and labels as formatted by Key.
+func (r *Registry) CalcAll() map[string]*Metrics {
+	metrics := map[string]*Metrics{}
+	r.Each(func(name string, labels []string, t *Tachymeter) {
+		metrics[key(name, labels)] = t.Calc()
+	})
+
+	return metrics
+}
@@ -0,0 +1,127 @@
+package tachymeter_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := tachymeter.NewRegistry(&tachymeter.Config{Size: 10})
+
+	users := reg.Get("db.query", "table", "users")
+	users.AddTime(time.Millisecond)
+
+	// Label order isn't significant.
+	if reg.Get("db.query", "table", "users") != users {
+		t.Error("Expected the same Tachymeter")
+	}
+
+	a := reg.Get("http", "method", "GET", "path", "/")
+	b := reg.Get("http", "path", "/", "method", "GET")
+	if a != b {
+		t.Error("Expected the same Tachymeter")
+	}
+
+	reg.Get("db.query", "table", "orders").AddTime(2 * time.Millisecond)
+	reg.Get("db.query", "table", "orders").AddTime(2 * time.Millisecond)
+
+	var names []string
+	reg.Each(func(name string, labels []string, _ *tachymeter.Tachymeter) {
+		names = append(names, tachymeter.Key(name, labels...))
+	})
+
+	expected := []string{
+		"db.query{table=orders}",
+		"db.query{table=users}",
+		"http{method=GET,path=/}",
+	}
+
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, names)
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected %s, got %s\n", expected[i], names[i])
+		}
+	}
+
+	all := reg.CalcAll()
+
+	if c := all[tachymeter.Key("db.query", "table", "orders")].Count; c != 2 {
+		t.Errorf("Expected 2, got %d\n", c)
+	}
+
+	if c := all["db.query{table=users}"].Count; c != 1 {
+		t.Errorf("Expected 1, got %d\n", c)
+	}
+
+	if c := all["http{method=GET,path=/}"].Count; c != 0 {
+		t.Errorf("Expected 0, got %d\n", c)
+	}
+}
+
+func TestRegistryConcurrentGet(t *testing.T) {
+	reg := tachymeter.NewRegistry(&tachymeter.Config{Size: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				reg.Get("op").AddTime(time.Millisecond)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if c := reg.CalcAll()["op"].Count; c != 800 {
+		t.Errorf("Expected 800, got %d\n", c)
+	}
+}
+
+func TestRegistryKeyEscaping(t *testing.T) {
+	reg := tachymeter.NewRegistry(&tachymeter.Config{Size: 10})
+
+	a := reg.Get("x", "a", "b,c=d")
+	b := reg.Get("x", "a", "b", "c", "d")
+
+	if a == b {
+		t.Error("Expected distinct Tachymeters")
+	}
+
+	if k := tachymeter.Key("x", "a", "b,c=d"); k != `x{a=b\,c\=d}` {
+		t.Errorf(`Expected x{a=b\,c\=d}, got %s`+"\n", k)
+	}
+
+	if k := tachymeter.Key("x{a=b}"); k == tachymeter.Key("x", "a", "b") {
+		t.Errorf("Expected distinct keys, got %s\n", k)
+	}
+
+	if n := len(reg.CalcAll()); n != 2 {
+		t.Errorf("Expected 2, got %d\n", n)
+	}
+}
+
+func TestRegistryLabelsUnmodified(t *testing.T) {
+	reg := tachymeter.NewRegistry(&tachymeter.Config{Size: 10})
+
+	labels := make([]string, 3, 4)
+	labels[0], labels[1], labels[2] = "a", "b", "c"
+	labels[:4][3] = "d"
+
+	reg.Get("y", labels...)
+
+	if labels[:4][3] != "d" {
+		t.Errorf("Expected d, got %q\n", labels[:4][3])
+	}
+
+	if k := tachymeter.Key("y", labels...); k != "y{a=b,c=}" {
+		t.Errorf("Expected y{a=b,c=}, got %s\n", k)
+	}
+}
@@ -0,0 +1,183 @@
+package tachymeter
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// reservoir is a uniform random sample of up to
+// size of all events observed, maintained
+// with Li's Algorithm L. Unlike the ring, every
+// event observed is equally likely to be sampled
+// regardless of when it occurred.
+type reservoir struct {
+	times timeSlice
+	size  int
+	count uint64
+	next  uint64  // The (1-indexed) event to be sampled next.
+	w     float64 // Algorithm L state.
+	rand  *rand.Rand
+	// weights is set when reservoirs of differing
+	// sample rates have been merged.
+	weights []float64
+}
+
+// newReservoir returns a *reservoir
+// that samples up to size events.
+func newReservoir(size int) *reservoir {
+	return &reservoir{
+		size: size,
+		rand: rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// u returns a uniform random value in (0, 1].
+func (r *reservoir) u() float64 {
+	return 1 - r.rand.Float64()
+}
+
+// skip advances next past the events
+// that won't be sampled.
+func (r *reservoir) skip() {
+	k := float64(r.size)
+	r.w *= math.Exp(math.Log(r.u()) / k)
+	r.next += uint64(math.Floor(math.Log(r.u())/math.Log(1-r.w))) + 1
+}
+
+func (r *reservoir) add(t time.Duration) {
+	r.count++
+
+	// Fill the reservoir.
+	if len(r.times) < r.size {
+		r.times = append(r.times, t)
+		if len(r.times) == r.size {
+			r.w = 1
+			r.next = r.count
+			r.skip()
+		}
+		return
+	}
+
+	if r.count == r.next {
+		r.times[r.rand.Intn(len(r.times))] = t
+		r.skip()
+	}
+}
+
+func (r *reservoir) reset() {
+	r.times = r.times[:0]
+	r.count = 0
+	r.weights = nil
+}
+
+func (r *reservoir) clone() recorder {
+	c := *r
+	c.times = append(timeSlice(nil), r.times...)
+	if r.weights != nil {
+		c.weights = append([]float64(nil), r.weights...)
+	}
+
+	return &c
+}
+
+// weight returns the number of events
+// represented by times[i].
+func (r *reservoir) weight(i int) float64 {
+	if r.weights != nil {
+		return r.weights[i]
+	}
+	return float64(r.count) / float64(len(r.times))
+}
+
+// merge appends the samples of o to r. Each
+// sample is weighted by the number of events it
+// represents in its source reservoir so that the
+// merged sample remains uniform. The merged
+// reservoir isn't intended for further writes.
+func (r *reservoir) merge(o recorder) {
+	or := o.(*reservoir)
+	if len(or.times) == 0 {
+		return
+	}
+	if len(r.times) == 0 {
+		*r = *or.clone().(*reservoir)
+		return
+	}
+
+	var weights []float64
+	for i := range r.times {
+		weights = append(weights, r.weight(i))
+	}
+	for i := range or.times {
+		weights = append(weights, or.weight(i))
+	}
+
+	r.times = append(r.times[:len(r.times):len(r.times)], or.times...)
+	r.count += or.count
+	r.weights = weights
+}
+
+func (r *reservoir) encode(e *encoder) {
+	e.uvarint(r.count)
+	e.uvarint(r.next)
+	e.float(r.w)
+	e.uvarint(uint64(len(r.times)))
+	for _, t := range r.times {
+		e.duration(t)
+	}
+	e.uvarint(uint64(len(r.weights)))
+	for _, w := range r.weights {
+		e.float(w)
+	}
+}
+
+func (r *reservoir) decode(d *decoder) {
+	r.count = d.uvarint()
+	r.next = d.uvarint()
+	r.w = d.float()
+
+	n := d.length()
+	if n > r.size {
+		d.fail()
+		return
+	}
+
+	r.times = make(timeSlice, n)
+	for i := range r.times {
+		r.times[i] = d.duration()
+	}
+
+	// Weights are either absent or
+	// held for every sample.
+	r.weights = nil
+	switch m := d.length(); m {
+	case 0:
+	case n:
+		r.weights = make([]float64, m)
+		for i := range r.weights {
+			r.weights[i] = d.weight()
+		}
+	default:
+		d.fail()
+	}
+}
+
+func (r *reservoir) summary() *summary {
+	times := make(timeSlice, len(r.times))
+	copy(times, r.times)
+
+	if r.weights == nil {
+		return newSummary(times, nil, r.count)
+	}
+
+	// Normalize weights such that they
+	// sum to the sample count.
+	scale := float64(len(r.times)) / float64(r.count)
+	weights := make([]float64, len(r.weights))
+	for i, w := range r.weights {
+		weights[i] = w * scale
+	}
+
+	return newSummary(times, weights, r.count)
+}
@@ -0,0 +1,72 @@
+package tachymeter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestReservoir(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Size:   1000,
+			Mode:   tachymeter.ModeReservoir,
+			Shards: shards,
+		})
+
+		// A steadily increasing duration. A window of
+		// the last 1000 events would average ~99.5ms.
+		for i := 0; i < 100000; i++ {
+			ta.AddTime(time.Duration(i) * time.Microsecond)
+		}
+
+		metrics := ta.Calc()
+
+		if metrics.Mode != tachymeter.ModeReservoir {
+			t.Errorf("Expected reservoir, got %s\n", metrics.Mode)
+		}
+
+		if metrics.Count != 100000 {
+			t.Errorf("Expected 100000, got %d\n", metrics.Count)
+		}
+
+		if metrics.Samples != 1000 {
+			t.Errorf("Expected 1000, got %d\n", metrics.Samples)
+		}
+
+		// The sample mean of a uniform sample of 0-100ms
+		// has a standard error of ~0.9ms.
+		if !within(metrics.Time.Avg, 50*time.Millisecond, 0.1) {
+			t.Errorf("Expected ~50ms, got %s\n", metrics.Time.Avg)
+		}
+
+		if !within(metrics.Time.P50, 50*time.Millisecond, 0.1) {
+			t.Errorf("Expected ~50ms, got %s\n", metrics.Time.P50)
+		}
+
+		ta.Reset()
+
+		if ta.Calc().Count != 0 {
+			t.Error("Expected 0 after reset")
+		}
+	}
+}
+
+func TestReservoirUnfilled(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 10, Mode: tachymeter.ModeReservoir})
+
+	for i := 1; i <= 5; i++ {
+		ta.AddTime(time.Duration(i) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+
+	if metrics.Samples != 5 {
+		t.Errorf("Expected 5, got %d\n", metrics.Samples)
+	}
+
+	if metrics.Time.Cumulative != 15*time.Millisecond {
+		t.Errorf("Expected 15ms, got %s\n", metrics.Time.Cumulative)
+	}
+}
@@ -0,0 +1,189 @@
+package tachymeter
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// OutlierMethod selects how outlying
+// events are detected.
+type OutlierMethod int
+
+// Outlier detection methods.
+const (
+	// OutliersNone disables outlier detection.
+	OutliersNone OutlierMethod = iota
+	// OutliersTukey flags events beyond Tukey's
+	// fences, p25 - k*IQR and p75 + k*IQR.
+	OutliersTukey
+	// OutliersMAD flags events with a modified
+	// z-score, 0.6745 * |t - p50| / MAD, above k.
+	OutliersMAD
+)
+
+// String returns the name of the OutlierMethod.
+func (o OutlierMethod) String() string {
+	switch o {
+	case OutliersNone:
+		return "none"
+	case OutliersTukey:
+		return "tukey"
+	case OutliersMAD:
+		return "mad"
+	default:
+		return "unknown"
+	}
+}
+
+// Outliers describes the events
+// detected as outliers.
+type Outliers struct {
+	Method OutlierMethod
+	Low    time.Duration   // Events below Low are outliers.
+	High   time.Duration   // Events above High are outliers.
+	Count  int             // Number of outlying events.
+	Values []time.Duration // Outlying sample event durations, ascending.
+}
+
+// MarshalJSON defines the output formatting
+// for the JSON() method.
+func (o *Outliers) MarshalJSON() ([]byte, error) {
+	values := make([]string, len(o.Values))
+	for i, v := range o.Values {
+		values[i] = v.String()
+	}
+
+	return json.Marshal(&struct {
+		Method string
+		Low    string
+		High   string
+		Count  int
+		Values []string
+	}{
+		Method: o.Method.String(),
+		Low:    o.Low.String(),
+		High:   o.High.String(),
+		Count:  o.Count,
+		Values: values,
+	})
+}
+
+// Trimmed holds statistics calculated with a
+// fraction of the events at each tail either
+// discarded (trimmed) or replaced by the nearest
+// remaining event duration (winsorized).
+type Trimmed struct {
+	Fraction         float64       // Fraction of events trimmed from each tail.
+	Avg              time.Duration // Trimmed event duration average.
+	StdDev           time.Duration // Trimmed standard deviation.
+	WinsorizedAvg    time.Duration // Winsorized event duration average.
+	WinsorizedStdDev time.Duration // Winsorized standard deviation.
+}
+
+// MarshalJSON defines the output formatting
+// for the JSON() method.
+func (t *Trimmed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Fraction         float64
+		Avg              string
+		StdDev           string
+		WinsorizedAvg    string
+		WinsorizedStdDev string
+	}{
+		Fraction:         t.Fraction,
+		Avg:              t.Avg.String(),
+		StdDev:           t.StdDev.String(),
+		WinsorizedAvg:    t.WinsorizedAvg.String(),
+		WinsorizedStdDev: t.WinsorizedStdDev.String(),
+	})
+}
+
+// outliers returns the events of the summary
+// lying beyond the fences of method m. k is
+// the fence threshold; if 0, the method's
+// default is used.
+func (s *summary) outliers(m OutlierMethod, k float64) *Outliers {
+	o := &Outliers{Method: m}
+
+	switch m {
+	case OutliersTukey:
+		if k <= 0 {
+			k = 1.5
+		}
+		q1, q3 := s.p(0.25), s.p(0.75)
+		d := time.Duration(k * float64(q3-q1))
+		o.Low, o.High = q1-d, q3+d
+	case OutliersMAD:
+		if k <= 0 {
+			k = 3.5
+		}
+		med := s.median()
+		d := time.Duration(k * float64(s.mad()) / 0.6745)
+		o.Low, o.High = med-d, med+d
+	}
+
+	var count float64
+	for i, t := range s.times {
+		if t < o.Low || t > o.High {
+			o.Values = append(o.Values, t)
+			count += s.w(i)
+		}
+	}
+	o.Count = int(math.Round(count))
+
+	return o
+}
+
+// trimmed returns the *Trimmed statistics with
+// the fraction f of events trimmed from each tail.
+func (s *summary) trimmed(f float64) *Trimmed {
+	g := math.Floor(s.n * f)
+	// Events ranked in (lo, hi] are retained.
+	lo, hi := g, s.n-g
+	low, high := s.rank(lo+1), s.rank(hi)
+
+	// winsorize returns the portion of the events
+	// at s.times[i] retained when trimming, and t
+	// clamped to the retained range.
+	var c float64
+	winsorize := func(i int, t time.Duration) (float64, float64) {
+		prev := c
+		c += s.w(i)
+		in := math.Max(math.Min(c, hi)-math.Max(prev, lo), 0)
+
+		switch {
+		case t < low:
+			t = low
+		case t > high:
+			t = high
+		}
+
+		return in, float64(t)
+	}
+
+	var tSum, tN, wSum float64
+	for i, t := range s.times {
+		in, wt := winsorize(i, t)
+		tSum += float64(t) * in
+		tN += in
+		wSum += wt * s.w(i)
+	}
+	tMean, wMean := tSum/tN, wSum/s.n
+
+	var tVar, wVar float64
+	c = 0
+	for i, t := range s.times {
+		in, wt := winsorize(i, t)
+		tVar += math.Pow(float64(t)-tMean, 2) * in
+		wVar += math.Pow(wt-wMean, 2) * s.w(i)
+	}
+
+	return &Trimmed{
+		Fraction:         f,
+		Avg:              time.Duration(tMean),
+		StdDev:           time.Duration(math.Sqrt(tVar / tN)),
+		WinsorizedAvg:    time.Duration(wMean),
+		WinsorizedStdDev: time.Duration(math.Sqrt(wVar / s.n)),
+	}
+}
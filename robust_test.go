@@ -0,0 +1,93 @@
+package tachymeter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+var robustTimes = []int{13, 1, 2, 40, 1, 5, 3, 8, 1, 2}
+
+func TestOutliers(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:     10,
+		Outliers: tachymeter.OutliersTukey,
+	})
+
+	for _, d := range robustTimes {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+	o := metrics.Outliers
+
+	if o.Low != -9500*time.Microsecond || o.High != 18500*time.Microsecond {
+		t.Errorf("Expected -9.5ms, 18.5ms, got %s, %s\n", o.Low, o.High)
+	}
+
+	if o.Count != 1 || len(o.Values) != 1 || o.Values[0] != 40*time.Millisecond {
+		t.Errorf("Expected [40ms], got %d %v\n", o.Count, o.Values)
+	}
+
+	if !strings.Contains(metrics.String(), "\nOutliers:\t1 (< -9.5ms or > 18.5ms)") {
+		t.Errorf("Expected outliers in output, got %s\n", metrics.String())
+	}
+
+	ta = tachymeter.New(&tachymeter.Config{
+		Size:             10,
+		Outliers:         tachymeter.OutliersMAD,
+		OutlierThreshold: 2,
+	})
+
+	for _, d := range robustTimes {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	o = ta.Calc().Outliers
+
+	if o.High != 8930318*time.Nanosecond {
+		t.Errorf("Expected 8.930318ms, got %s\n", o.High)
+	}
+
+	if o.Count != 2 || len(o.Values) != 2 || o.Values[0] != 13*time.Millisecond {
+		t.Errorf("Expected [13ms 40ms], got %d %v\n", o.Count, o.Values)
+	}
+}
+
+func TestTrimmed(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 10, Trim: 0.1})
+
+	for _, d := range robustTimes {
+		ta.AddTime(time.Duration(d) * time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+	tr := metrics.Trimmed
+
+	if metrics.Outliers != nil {
+		t.Errorf("Expected nil, got %v\n", metrics.Outliers)
+	}
+
+	if tr.Avg != 4375*time.Microsecond {
+		t.Errorf("Expected 4.375ms, got %s\n", tr.Avg)
+	}
+
+	if tr.StdDev != 3935019 {
+		t.Errorf("Expected 3935019, got %d\n", tr.StdDev)
+	}
+
+	if tr.WinsorizedAvg != 4900*time.Microsecond {
+		t.Errorf("Expected 4.9ms, got %s\n", tr.WinsorizedAvg)
+	}
+
+	if tr.WinsorizedStdDev != 4548626 {
+		t.Errorf("Expected 4548626, got %d\n", tr.WinsorizedStdDev)
+	}
+
+	// The raw view is unaffected.
+	if metrics.Time.Max != 40*time.Millisecond {
+		t.Errorf("Expected 40ms, got %s\n", metrics.Time.Max)
+	}
+}
@@ -0,0 +1,96 @@
+package tachymeter
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// cacheLine is the assumed CPU cache line size
+// used to pad shards from one another.
+const cacheLine = 64
+
+// shard is an independently locked recorder.
+// Concurrent writers are spread across shards
+// so that they don't contend on a single lock
+// or counter.
+type shard struct {
+	sync.Mutex
+	rec recorder
+	_   [cacheLine]byte
+}
+
+// newShards returns the shards for the
+// recording mode and shard count specified
+// in c.
+func newShards(c *Config) []*shard {
+	n := c.Shards
+	if n < 1 {
+		n = 1
+	}
+
+	shards := make([]*shard, n)
+	for i := range shards {
+		shards[i] = &shard{rec: newRecorder(c, n)}
+	}
+
+	return shards
+}
+
+// shardSeed is the last seed handed
+// to a shardRand state.
+var shardSeed uint64
+
+// shardRand holds xorshift states used to select
+// shards. A sync.Pool caches states per P, so
+// concurrent writers don't share a lock or
+// cache line.
+var shardRand = sync.Pool{
+	New: func() interface{} {
+		s := atomic.AddUint64(&shardSeed, 0x9e3779b97f4a7c15) | 1
+		return &s
+	},
+}
+
+// shard returns a shard selected at random.
+func (m *Tachymeter) shard() *shard {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+
+	x := shardRand.Get().(*uint64)
+	*x ^= *x << 13
+	*x ^= *x >> 7
+	*x ^= *x << 17
+	s := m.shards[*x%uint64(len(m.shards))]
+	shardRand.Put(x)
+
+	return s
+}
+
+// collect returns a copy of all recorded state
+// merged into a single recorder. The caller
+// must hold the Tachymeter lock.
+func (m *Tachymeter) collect() recorder {
+	if m.shards == nil {
+		r := &ring{times: m.Times, count: m.Count}
+		return r.clone()
+	}
+
+	// All shards are locked for the duration
+	// of the copy so that the result reflects
+	// a single point in time.
+	for _, s := range m.shards {
+		s.Lock()
+	}
+
+	r := m.shards[0].rec.clone()
+	for _, s := range m.shards[1:] {
+		r.merge(s.rec)
+	}
+
+	for _, s := range m.shards {
+		s.Unlock()
+	}
+
+	return r
+}
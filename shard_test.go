@@ -0,0 +1,92 @@
+package tachymeter_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestShardedCalc(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 100, Shards: 4})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 1; n <= 10; n++ {
+				ta.AddTime(time.Duration(n) * time.Millisecond)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	metrics := ta.Calc()
+
+	if metrics.Count != 40 {
+		t.Errorf("Expected 40, got %d\n", metrics.Count)
+	}
+
+	if metrics.Samples != 40 {
+		t.Errorf("Expected 40, got %d\n", metrics.Samples)
+	}
+
+	if metrics.Time.Cumulative != 220*time.Millisecond {
+		t.Errorf("Expected 220ms, got %s\n", metrics.Time.Cumulative)
+	}
+
+	if metrics.Time.Min != time.Millisecond {
+		t.Errorf("Expected 1ms, got %s\n", metrics.Time.Min)
+	}
+
+	if metrics.Time.Max != 10*time.Millisecond {
+		t.Errorf("Expected 10ms, got %s\n", metrics.Time.Max)
+	}
+
+	ta.Reset()
+
+	if ta.Calc().Count != 0 {
+		t.Error("Expected 0 after reset")
+	}
+}
+
+func TestShardedWindow(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 8, Shards: 4})
+
+	for i := 0; i < 1000; i++ {
+		ta.AddTime(time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+
+	if metrics.Count != 1000 {
+		t.Errorf("Expected 1000, got %d\n", metrics.Count)
+	}
+
+	// Samples is bounded by the sum of the shard windows.
+	if metrics.Samples > 8 {
+		t.Errorf("Expected at most 8, got %d\n", metrics.Samples)
+	}
+}
+
+func TestShardedSeeded(t *testing.T) {
+	// Seeding the global source mustn't affect
+	// shard selection.
+	rand.Seed(1)
+
+	ta := tachymeter.New(&tachymeter.Config{Size: 100, Shards: 8})
+
+	for i := 0; i < 10000; i++ {
+		ta.AddTime(time.Millisecond)
+	}
+
+	// Shards hold 13 samples each; all
+	// should have been written to.
+	if metrics := ta.Calc(); metrics.Samples != 104 {
+		t.Errorf("Expected 104, got %d\n", metrics.Samples)
+	}
+}
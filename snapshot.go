@@ -0,0 +1,196 @@
+package tachymeter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// snapshotVersion is the Tachymeter
+// binary encoding format version.
+// Version 2 adds the corrected flag.
+const snapshotVersion byte = 2
+
+// MarshalBinary encodes the Tachymeter's configuration
+// and recorded state in a versioned binary format,
+// e.g. to checkpoint a run or to ship raw samples
+// elsewhere for analysis.
+func (m *Tachymeter) MarshalBinary() ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	e := &encoder{}
+	e.WriteByte(snapshotVersion)
+
+	config, err := json.Marshal(m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	e.bytes(config)
+	e.uvarint(m.Size)
+	e.uvarint(uint64(m.HBins))
+	e.duration(m.WallTime)
+	e.uvarint(uint64(atomic.LoadUint32(&m.corrected)))
+
+	if m.shards == nil {
+		e.uvarint(0)
+		e.uvarint(m.Count)
+		e.uvarint(uint64(len(m.Times)))
+		for _, t := range m.Times {
+			e.duration(t)
+		}
+	} else {
+		e.uvarint(uint64(len(m.shards)))
+		for _, s := range m.shards {
+			s.Lock()
+			s.rec.encode(e)
+			s.Unlock()
+		}
+	}
+
+	// Outcome Tachymeters are nested
+	// snapshots.
+	o := m.getOutcomes()
+	if o == nil {
+		e.uvarint(0)
+		return e.Bytes(), nil
+	}
+
+	e.uvarint(1)
+	for _, t := range []*Tachymeter{o.success, o.failure} {
+		b, err := t.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(b)
+	}
+
+	return e.Bytes(), nil
+}
+
+// maxSnapshotHDRCounts bounds the HDR counts
+// allocated across all shards when restoring
+// a snapshot, as HDR configs aren't otherwise
+// bounded by the size of the snapshot.
+const maxSnapshotHDRCounts = 1 << 25
+
+// UnmarshalBinary restores a Tachymeter encoded with
+// MarshalBinary, replacing its configuration and
+// state. UnmarshalBinary may be called on a zero
+// value Tachymeter. Unlike other methods, it isn't
+// safe to call concurrently with any other use of
+// the Tachymeter. HDR snapshots holding more than
+// 2^25 counts across all shards are rejected.
+func (m *Tachymeter) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data)
+
+	version := d.byte()
+	if d.err != nil {
+		return d.err
+	}
+	if version < 1 || version > snapshotVersion {
+		return fmt.Errorf("tachymeter: unsupported snapshot encoding version %d", version)
+	}
+
+	var c Config
+	config := d.bytes()
+	if d.err != nil {
+		return d.err
+	}
+	if err := json.Unmarshal(config, &c); err != nil {
+		return err
+	}
+
+	// New allocates shards, and ring storage for
+	// ModeWindow, sized by the config. Each shard
+	// and sample is encoded in at least one byte,
+	// which bounds these by the remaining input.
+	// Recorders with storage sized by their own
+	// parameters are bounded separately.
+	remaining := d.r.Len()
+	switch {
+	case c.Size < 0, c.Shards < 0, c.HBins < 0:
+		return ErrCorrupt
+	case c.Shards > remaining:
+		return ErrCorrupt
+	case c.Mode == ModeWindow && c.Window <= 0 && c.Size > remaining:
+		return ErrCorrupt
+	case c.Mode == ModeTDigest && c.Compression > maxCompression:
+		return ErrCorrupt
+	case c.Mode == ModeHDR:
+		shards := c.Shards
+		if shards < 1 {
+			shards = 1
+		}
+		if _, n := hdrLayout(&c); n > maxSnapshotHDRCounts/shards {
+			return ErrCorrupt
+		}
+	}
+
+	t := New(&c)
+	if d.uvarint() != t.Size {
+		d.fail()
+	}
+	if hBins := d.uvarint(); hBins <= math.MaxInt32 {
+		t.HBins = int(hBins)
+	} else {
+		d.fail()
+	}
+	t.WallTime = d.duration()
+	if version >= 2 {
+		t.corrected = uint32(d.uvarint())
+	}
+
+	shards := d.length()
+	if shards != len(t.shards) {
+		return ErrCorrupt
+	}
+
+	if t.shards == nil {
+		t.Count = d.uvarint()
+		t.Times = make(timeSlice, d.length())
+		for i := range t.Times {
+			t.Times[i] = d.duration()
+		}
+		if uint64(len(t.Times)) != t.Size {
+			d.fail()
+		}
+	} else {
+		for _, s := range t.shards {
+			s.rec.decode(d)
+		}
+	}
+
+	var o *outcomes
+	if d.uvarint() == 1 {
+		o = &outcomes{success: &Tachymeter{}, failure: &Tachymeter{}}
+		for _, ot := range []*Tachymeter{o.success, o.failure} {
+			b := d.bytes()
+			if d.err != nil {
+				break
+			}
+			if err := ot.UnmarshalBinary(b); err != nil {
+				return err
+			}
+		}
+	}
+
+	if d.err != nil {
+		return d.err
+	}
+
+	m.Size = t.Size
+	m.Times = t.Times
+	m.Count = t.Count
+	m.WallTime = t.WallTime
+	m.HBins = t.HBins
+	m.config = t.config
+	atomic.StoreUint32(&m.corrected, t.corrected)
+	m.shards = t.shards
+	// A typed nil clears any existing outcomes.
+	m.outcomes.Store(o)
+
+	return nil
+}
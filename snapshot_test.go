@@ -0,0 +1,212 @@
+package tachymeter_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestSnapshot(t *testing.T) {
+	configs := []*tachymeter.Config{
+		{Size: 50, HBins: 5},
+		{Size: 50, Shards: 4},
+		{Mode: tachymeter.ModeHDR, Shards: 2},
+		{Mode: tachymeter.ModeTDigest},
+		{Mode: tachymeter.ModeDDSketch},
+		{Size: 50, Mode: tachymeter.ModeReservoir},
+		{Size: 50, Mode: tachymeter.ModeDecay},
+		{Size: 50, Window: time.Minute},
+	}
+
+	for _, c := range configs {
+		ta := tachymeter.New(c)
+		for i := 1; i <= 100; i++ {
+			ta.AddTime(time.Duration(i) * time.Millisecond)
+		}
+		ta.AddTimeResult(time.Millisecond, errors.New("failed"))
+		ta.SetWallTime(time.Second)
+
+		data, err := ta.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored := &tachymeter.Tachymeter{}
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %s\n", c.Mode, err)
+		}
+
+		expected, got := ta.Calc().JSON(), restored.Calc().JSON()
+		if expected != got {
+			t.Errorf("%s: expected %s, got %s\n", c.Mode, expected, got)
+		}
+
+		// The restored Tachymeter remains usable.
+		restored.AddTime(time.Millisecond)
+		if n := restored.Calc().Count; n != 102 {
+			t.Errorf("%s: expected 102, got %d\n", c.Mode, n)
+		}
+	}
+}
+
+func TestSnapshotFields(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 3, HBins: 4})
+	ta.AddTime(time.Millisecond)
+	ta.AddTime(2 * time.Millisecond)
+	ta.SetWallTime(time.Second)
+
+	data, _ := ta.MarshalBinary()
+
+	restored := &tachymeter.Tachymeter{}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Size != 3 || restored.Count != 2 || restored.HBins != 4 || restored.WallTime != time.Second {
+		t.Errorf("Unexpected restored fields: %+v\n", restored)
+	}
+
+	if restored.Times[1] != 2*time.Millisecond {
+		t.Errorf("Expected 2ms, got %s\n", restored.Times[1])
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeHDR})
+	ta.AddTime(time.Millisecond)
+
+	data, _ := ta.MarshalBinary()
+
+	restored := &tachymeter.Tachymeter{}
+	if err := restored.UnmarshalBinary(data[:len(data)/2]); err == nil {
+		t.Error("Expected an error")
+	}
+
+	if err := restored.UnmarshalBinary([]byte{99}); err == nil {
+		t.Error("Expected a version error")
+	}
+}
+
+func TestSnapshotCorrected(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 10})
+	ta.AddTimeCorrected(30*time.Millisecond, 10*time.Millisecond)
+
+	data, _ := ta.MarshalBinary()
+
+	restored := &tachymeter.Tachymeter{}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if metrics := restored.Calc(); !metrics.Corrected || metrics.Count != 3 {
+		t.Errorf("Expected 3 corrected events, got %d %t\n", metrics.Count, metrics.Corrected)
+	}
+}
+
+func TestSnapshotInvalidConfig(t *testing.T) {
+	for _, config := range []string{
+		`{"Size":-1}`,
+		`{"Size":1000000000000}`,
+		`{"Shards":-1}`,
+		`{"Shards":1000000000000,"Mode":1}`,
+		`{"HBins":-1}`,
+		`{"Mode":2,"Compression":1e15}`,
+		`{"Mode":1,"HDRDigits":5,"Shards":64}`,
+	} {
+		// Padding leaves room for the shards
+		// and samples the config calls for.
+		data := append([]byte{2, byte(len(config))}, config...)
+		data = append(data, make([]byte, 128)...)
+
+		restored := &tachymeter.Tachymeter{}
+		if err := restored.UnmarshalBinary(data); err != tachymeter.ErrCorrupt {
+			t.Errorf("%s: Expected ErrCorrupt, got %v\n", config, err)
+		}
+	}
+
+	ta := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeHDR, HDRDigits: 5})
+	data, _ := ta.MarshalBinary()
+
+	if err := (&tachymeter.Tachymeter{}).UnmarshalBinary(data); err != nil {
+		t.Errorf("Expected a 5 digit HDR to restore, got %v\n", err)
+	}
+}
+
+// encodeSnapshot returns a single shard snapshot
+// of a tachymeter with the given config and size,
+// with the recorder encoding rec.
+func encodeSnapshot(config string, size uint64, rec []byte) []byte {
+	b := append([]byte{2, byte(len(config))}, config...)
+	b = binary.AppendUvarint(b, size)
+	b = binary.AppendUvarint(b, 10)
+	b = binary.AppendVarint(b, 0)
+	b = binary.AppendUvarint(b, 0)
+	b = binary.AppendUvarint(b, 1)
+	b = append(b, rec...)
+
+	return binary.AppendUvarint(b, 0)
+}
+
+// encodeWeighted returns a reservoir encoding
+// of three samples with the given weights.
+func encodeWeighted(weights ...float64) []byte {
+	b := []byte{3, 0}
+	b = binary.AppendUvarint(b, math.Float64bits(1))
+	b = binary.AppendUvarint(b, 3)
+	for i := 1; i <= 3; i++ {
+		b = binary.AppendVarint(b, int64(i)*int64(time.Millisecond))
+	}
+	b = binary.AppendUvarint(b, uint64(len(weights)))
+	for _, w := range weights {
+		b = binary.AppendUvarint(b, math.Float64bits(w))
+	}
+
+	return b
+}
+
+func TestSnapshotInvalidWeights(t *testing.T) {
+	config := `{"Size":3,"Mode":4}`
+
+	for _, weights := range [][]float64{nil, {1, 2, 3}} {
+		restored := &tachymeter.Tachymeter{}
+		if err := restored.UnmarshalBinary(encodeSnapshot(config, 3, encodeWeighted(weights...))); err != nil {
+			t.Fatal(err)
+		}
+		if metrics := restored.Calc(); metrics.Samples != 3 {
+			t.Errorf("Expected 3 samples, got %d\n", metrics.Samples)
+		}
+	}
+
+	for _, weights := range [][]float64{
+		{1},
+		{1, 2, 3, 4},
+		{1, math.NaN(), 3},
+		{1, 0, 3},
+		{1, -2, 3},
+		{1, math.Inf(1), 3},
+	} {
+		restored := &tachymeter.Tachymeter{}
+		if err := restored.UnmarshalBinary(encodeSnapshot(config, 3, encodeWeighted(weights...))); err != tachymeter.ErrCorrupt {
+			t.Errorf("%v: Expected ErrCorrupt, got %v\n", weights, err)
+		}
+	}
+}
+
+func TestSnapshotAccuracyMismatch(t *testing.T) {
+	config := `{"Mode":3,"RelativeAccuracy":0.01}`
+	size := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeDDSketch}).Size
+
+	restored := &tachymeter.Tachymeter{}
+	if err := restored.UnmarshalBinary(encodeSnapshot(config, size, encodeSketch(0.01, 1, 0, 691, 1)[1:])); err != nil {
+		t.Fatal(err)
+	}
+
+	err := restored.UnmarshalBinary(encodeSnapshot(config, size, encodeSketch(0.2, 1, 0, 17, 1)[1:]))
+	if err != tachymeter.ErrAccuracyMismatch {
+		t.Errorf("Expected ErrAccuracyMismatch, got %v\n", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,22 +16,176 @@ import (
 
 // Config holds tachymeter initialization
 // parameters. Size defines the sample capacity.
-// Tachymeter is thread safe.
+// Tachymeter is thread safe; AddTime, Calc and
+// Reset may be called concurrently.
 type Config struct {
 	Size  int
 	Safe  bool // Deprecated. Flag held on to as to not break existing users.
 	HBins int  // Histogram bins.
+	// HMode selects how histogram bins are sized.
+	// Defaults to HistogramLinear.
+	HMode HistogramMode
+	// HistogramExponential parameters. HStart is the upper
+	// bound of the first bin, defaulting to 1ms, and
+	// HFactor the growth factor of each subsequent bound,
+	// defaulting to 2.
+	HStart  time.Duration
+	HFactor float64
+	// HistogramCustom parameter. HBounds lists the upper
+	// bounds of each histogram bin, e.g. 1ms, 5ms, 10ms
+	// and 50ms.
+	HBounds []time.Duration
+	// Shards splits the sample window into n independently
+	// counted shards, spreading concurrent AddTime calls across
+	// them. Setting this to runtime.GOMAXPROCS(0) is a good
+	// starting point for heavily parallel writers. Each shard
+	// holds Size/Shards samples rounded up, so up to Shards-1
+	// samples more than Size may be held.
+	Shards int
+	Mode   Mode // Recording mode. Defaults to ModeWindow.
+	// ModeHDR parameters. HDRDigits is the number of significant
+	// decimal digits (1-5, default 3) maintained for values between
+	// HDRLowest (default 1ns) and HDRHighest (default 1h). Values
+	// outside of this range are clamped.
+	HDRDigits  int
+	HDRLowest  time.Duration
+	HDRHighest time.Duration
+	// ModeTDigest parameter. Compression bounds the number of
+	// centroids held (roughly 2-5x Compression). Higher values
+	// trade memory for tail percentile accuracy. Defaults to 100
+	// and is clamped to at most 100000.
+	Compression float64
+	// ModeDDSketch parameter. RelativeAccuracy is the maximum
+	// relative error of reported percentiles, e.g. 0.01 for
+	// 1%. Defaults to 0.01.
+	RelativeAccuracy float64
+	// ModeDecay parameter. Alpha is the per-second exponential
+	// decay rate; higher values weight recent events more
+	// heavily. Defaults to 0.015, which heavily biases results
+	// toward roughly the last 5 minutes.
+	Alpha float64
+	// Percentiles lists additional percentiles, e.g. 90 and
+	// 99.99, to calculate in Metrics.Percentiles.
+	Percentiles []float64
+	// Quantile selects the method used to calculate
+	// percentiles, including p50. Defaults to
+	// QuantileRounded. Ignored by ModeTDigest and
+	// ModeDDSketch, which estimate percentiles
+	// directly.
+	Quantile QuantileMethod
+	// Confidence is the confidence level, e.g. 0.95, of the
+	// percentile intervals reported in Metrics.Intervals.
+	// Intervals aren't calculated if unset.
+	Confidence float64
+	// ExtendedStats enables the additional distribution
+	// statistics reported in Metrics.Extended.
+	ExtendedStats bool
+	// Outliers selects a method of detecting outlying
+	// events, reported in Metrics.Outliers.
+	Outliers OutlierMethod
+	// OutlierThreshold is the multiple of the IQR beyond
+	// the quartiles (OutliersTukey, default 1.5) or the
+	// modified z-score (OutliersMAD, default 3.5) past
+	// which events are outliers.
+	OutlierThreshold float64
+	// Trim is the fraction of events, e.g. 0.05, trimmed
+	// from each tail for the statistics in Metrics.Trimmed.
+	// Must be less than 0.5.
+	Trim float64
+	// ApdexT is the target duration used to calculate
+	// the Apdex score in Metrics.Apdex. Apdex isn't
+	// calculated if unset.
+	ApdexT time.Duration
+	// Window selects ModeTimeWindow when set, summarizing
+	// only the events observed in the trailing Window
+	// duration. If Size is also set, it bounds the number of
+	// samples held. Defaults to 1m if Mode is explicitly set
+	// to ModeTimeWindow.
+	Window time.Duration
+}
+
+// Mode specifies how a Tachymeter stores
+// recorded event durations.
+type Mode int
+
+const (
+	// ModeWindow losslessly stores the last
+	// Size events.
+	ModeWindow Mode = iota
+	// ModeHDR counts events in a constant memory,
+	// log-linear HDR histogram. All events are
+	// summarized with bounded relative error.
+	ModeHDR
+	// ModeTDigest clusters events in a mergeable
+	// t-digest, which is most accurate at the
+	// extreme percentiles.
+	ModeTDigest
+	// ModeDDSketch counts events in a mergeable,
+	// serializable DDSketch with guaranteed relative
+	// error on all percentiles.
+	ModeDDSketch
+	// ModeReservoir holds a uniform random sample
+	// of up to Size of all events observed.
+	ModeReservoir
+	// ModeDecay holds a forward decay priority
+	// reservoir of up to Size events, where recent
+	// events are exponentially more likely to be
+	// sampled and weigh more heavily in outputs.
+	ModeDecay
+	// ModeTimeWindow holds the events observed
+	// in the trailing Window duration. It's
+	// selected by setting Config.Window.
+	ModeTimeWindow
+)
+
+// String returns the name of the Mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeWindow:
+		return "window"
+	case ModeHDR:
+		return "hdr"
+	case ModeTDigest:
+		return "tdigest"
+	case ModeDDSketch:
+		return "ddsketch"
+	case ModeReservoir:
+		return "reservoir"
+	case ModeDecay:
+		return "decay"
+	case ModeTimeWindow:
+		return "timewindow"
+	default:
+		return "unknown"
+	}
 }
 
 // Tachymeter holds event durations
 // and counts.
 type Tachymeter struct {
 	sync.Mutex
-	Size     uint64
+	Size uint64
+	// Times and Count hold the events of a ModeWindow
+	// Tachymeter with a single shard. Otherwise events
+	// are held by shards, and these remain empty; use
+	// the Count reported by Calc instead.
 	Times    timeSlice
 	Count    uint64
 	WallTime time.Duration
 	HBins    int
+	config   Config
+	shards   []*shard     // Non-nil when Config.Shards > 1 or Config.Mode != ModeWindow.
+	outcomes atomic.Value // Holds an *outcomes once AddTimeResult is called.
+	// corrected is set to 1 once AddTimeCorrected
+	// back-fills events.
+	corrected uint32
+}
+
+// outcomes holds Tachymeters for
+// successful and failed events.
+type outcomes struct {
+	success *Tachymeter
+	failure *Tachymeter
 }
 
 // timeslice holds time.Duration values.
@@ -41,6 +196,88 @@ func (p timeSlice) Len() int           { return len(p) }
 func (p timeSlice) Less(i, j int) bool { return int64(p[i]) < int64(p[j]) }
 func (p timeSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
+// Percentile is the event duration
+// at the Pth percentile.
+type Percentile struct {
+	P     float64 // Percentile, e.g. 99.9.
+	Value time.Duration
+}
+
+// Percentiles is an ordered list of Percentile.
+type Percentiles []Percentile
+
+// Label returns the percentile formatted
+// as e.g. 'p99.9'.
+func (p Percentile) Label() string {
+	return "p" + strconv.FormatFloat(p.P, 'f', -1, 64)
+}
+
+// ExtendedStats holds additional statistics
+// describing the shape of the event
+// duration distribution.
+type ExtendedStats struct {
+	GeoMean  time.Duration // Event duration geometric mean.
+	MAD      time.Duration // Median absolute deviation.
+	IQR      time.Duration // Interquartile range (p75-p25).
+	Skewness float64       // Population skewness.
+	Kurtosis float64       // Population excess kurtosis.
+	CV       float64       // Coefficient of variation (StdDev/Avg).
+}
+
+// MarshalJSON defines the output formatting
+// for the JSON() method.
+func (e *ExtendedStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		GeoMean  string
+		MAD      string
+		IQR      string
+		Skewness float64
+		Kurtosis float64
+		CV       float64
+	}{
+		GeoMean:  e.GeoMean.String(),
+		MAD:      e.MAD.String(),
+		IQR:      e.IQR.String(),
+		Skewness: e.Skewness,
+		Kurtosis: e.Kurtosis,
+		CV:       e.CV,
+	})
+}
+
+// Interval is a confidence interval.
+type Interval struct {
+	Low, High time.Duration
+}
+
+// String returns the interval formatted
+// as e.g. '[27ms, 31ms]'.
+func (i Interval) String() string {
+	return fmt.Sprintf("[%s, %s]", i.Low, i.High)
+}
+
+// MarshalJSON defines the output formatting
+// for the JSON() method.
+func (i Interval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Low  string
+		High string
+	}{
+		Low:  i.Low.String(),
+		High: i.High.String(),
+	})
+}
+
+// interval returns the formatted confidence
+// interval for the labeled percentile, if any,
+// with a leading space.
+func (m *Metrics) interval(label string) string {
+	i, ok := m.Intervals[label]
+	if !ok {
+		return ""
+	}
+	return " " + i.String()
+}
+
 // Histogram is a map["low-high duration"]count of events that
 // fall within the low-high time duration range.
 type Histogram []map[string]uint64
@@ -64,19 +301,46 @@ type Metrics struct {
 		StdDev     time.Duration // Standard deviation.
 		Range      time.Duration // Event duration range (Max-Min).
 	}
-	Rate struct {
+	Percentiles Percentiles // Percentiles listed in Config.Percentiles, in order.
+	// Confidence intervals of each percentile at the
+	// Confidence level, keyed by label, e.g. "p99".
+	// Nil unless Config.Confidence is set.
+	Intervals  map[string]Interval
+	Confidence float64
+	Rate       struct {
 		// Per-second rate based on event duration avg. via Metrics.Cumulative / Metrics.Samples.
 		// If SetWallTime was called, event duration avg = wall time / Metrics.Count
 		Second float64
 	}
-	Histogram        *Histogram    // Frequency distribution of event durations in len(Histogram) bins of HistogramBinSize.
-	HistogramBinSize time.Duration // The width of a histogram bin in time.
-	Samples          int           // Number of events included in the sample set.
-	Count            int           // Total number of events observed.
+	Extended         *ExtendedStats // Nil unless Config.ExtendedStats is set.
+	Outliers         *Outliers      // Nil unless Config.Outliers is set.
+	Trimmed          *Trimmed       // Nil unless Config.Trim is set.
+	Apdex            *Apdex         // Nil unless Config.ApdexT is set.
+	Histogram        *Histogram     // Frequency distribution of event durations in len(Histogram) bins of HistogramBinSize.
+	Bins             Bins           // The Histogram bins with numeric bounds.
+	Distribution     *Distribution  // The sorted event durations, for arbitrary queries.
+	HistogramBinSize time.Duration  // The width of a histogram bin in time. 0 unless Config.HMode is HistogramLinear.
+	Samples          int            // Number of events included in the sample set.
+	Count            int            // Total number of events observed.
+	Mode             Mode           // Recording mode of the source Tachymeter.
+	Corrected        bool           // Whether AddTimeCorrected back-filled events.
+	// Outcomes of events added with AddTimeResult. Success
+	// and Failure summarize successful and failed events and
+	// are nil unless AddTimeResult was called.
+	Errors    int      // Number of failed events.
+	ErrorRate float64  // Errors as a fraction of events with a recorded outcome.
+	Success   *Metrics // Successful events.
+	Failure   *Metrics // Failed events.
 }
 
 // New initializes a new Tachymeter.
 func New(c *Config) *Tachymeter {
+	if c.Window > 0 && c.Mode == ModeWindow {
+		tc := *c
+		tc.Mode = ModeTimeWindow
+		c = &tc
+	}
+
 	var hSize int
 	if c.HBins != 0 {
 		hSize = c.HBins
@@ -84,27 +348,144 @@ func New(c *Config) *Tachymeter {
 		hSize = 10
 	}
 
+	if c.Shards > 1 || c.Mode != ModeWindow {
+		// Times and Count are unused; events
+		// are held by the shards.
+		return &Tachymeter{
+			Size:   uint64(c.Size),
+			HBins:  hSize,
+			config: *c,
+			shards: newShards(c),
+		}
+	}
+
 	return &Tachymeter{
-		Size:  uint64(c.Size),
-		Times: make([]time.Duration, c.Size),
-		HBins: hSize,
+		Size:   uint64(c.Size),
+		Times:  make([]time.Duration, c.Size),
+		HBins:  hSize,
+		config: *c,
 	}
 }
 
 // Reset resets a Tachymeter
 // instance for reuse.
 func (m *Tachymeter) Reset() {
-	// This lock is obviously not needed for
-	// the m.Count update, rather to prevent a
-	// Tachymeter reset while Calc is being called.
 	m.Lock()
-	atomic.StoreUint64(&m.Count, 0)
+	m.Count = 0
+	atomic.StoreUint32(&m.corrected, 0)
+	for _, s := range m.shards {
+		s.Lock()
+		s.rec.reset()
+		s.Unlock()
+	}
 	m.Unlock()
+
+	if o := m.getOutcomes(); o != nil {
+		o.success.Reset()
+		o.failure.Reset()
+	}
 }
 
 // AddTime adds a time.Duration to Tachymeter.
+// AddTime is safe to call concurrently with
+// Calc and Reset.
 func (m *Tachymeter) AddTime(t time.Duration) {
-	m.Times[(atomic.AddUint64(&m.Count, 1)-1)%m.Size] = t
+	if m.shards != nil {
+		s := m.shard()
+		s.Lock()
+		s.rec.add(t)
+		s.Unlock()
+		return
+	}
+
+	m.Lock()
+	m.Times[m.Count%m.Size] = t
+	m.Count++
+	m.Unlock()
+}
+
+// AddTimeResult adds a time.Duration to Tachymeter
+// along with the outcome of the event, where a non-nil
+// err counts as a failure. Calc then reports error
+// counts and separate summaries of successful and
+// failed events.
+func (m *Tachymeter) AddTimeResult(t time.Duration, err error) {
+	m.AddTime(t)
+
+	o := m.getOutcomes()
+	if o == nil {
+		o = m.initOutcomes()
+	}
+
+	if err != nil {
+		o.failure.AddTime(t)
+	} else {
+		o.success.AddTime(t)
+	}
+}
+
+// AddTimeCorrected adds a time.Duration to Tachymeter,
+// correcting for coordinated omission where events are
+// issued at an expected interval. Events that would have
+// been issued while waiting on t are back-filled with
+// durations of t-expected, t-2*expected and so on down
+// to expected, as with HdrHistogram's
+// recordValueWithExpectedInterval.
+func (m *Tachymeter) AddTimeCorrected(t, expected time.Duration) {
+	if expected <= 0 || t < 2*expected {
+		m.AddTime(t)
+		return
+	}
+
+	atomic.StoreUint32(&m.corrected, 1)
+
+	// All events are added under
+	// a single lock acquisition.
+	var add func(time.Duration)
+	if m.shards != nil {
+		s := m.shard()
+		s.Lock()
+		defer s.Unlock()
+		add = s.rec.add
+	} else {
+		m.Lock()
+		defer m.Unlock()
+		add = func(t time.Duration) {
+			m.Times[m.Count%m.Size] = t
+			m.Count++
+		}
+	}
+
+	add(t)
+	for missing := t - expected; missing >= expected; missing -= expected {
+		add(missing)
+	}
+}
+
+// getOutcomes returns the outcome Tachymeters,
+// or nil if AddTimeResult hasn't been called.
+func (m *Tachymeter) getOutcomes() *outcomes {
+	o, _ := m.outcomes.Load().(*outcomes)
+	return o
+}
+
+// initOutcomes creates the outcome
+// Tachymeters if they don't exist.
+func (m *Tachymeter) initOutcomes() *outcomes {
+	m.Lock()
+	defer m.Unlock()
+
+	if o := m.getOutcomes(); o != nil {
+		return o
+	}
+
+	o := &outcomes{
+		success: New(&m.config),
+		failure: New(&m.config),
+	}
+	m.outcomes.Store(o)
+
+	return o
 }
 
 // SetWallTime optionally sets an elapsed wall time duration.
@@ -112,7 +493,9 @@ func (m *Tachymeter) AddTime(t time.Duration) {
 // This is useful for concurrent/parallelized events that overlap
 // in wall time and are writing to a shared Tachymeter instance.
 func (m *Tachymeter) SetWallTime(t time.Duration) {
+	m.Lock()
 	m.WallTime = t
+	m.Unlock()
 }
 
 // WriteHTML writes a histograph
@@ -125,15 +508,43 @@ func (m *Metrics) WriteHTML(p string) error {
 
 // String satisfies the String interface.
 func (m *Metrics) String() string {
-	return fmt.Sprintf(`%d samples of %d events
+	var corrected string
+	if m.Corrected {
+		corrected = " (corrected)"
+	}
+
+	s := fmt.Sprintf(`%d samples of %d events%s
 Cumulative:	%s
 HMean:		%s
 Avg.:		%s
-p50: 		%s
-p75:		%s
-p95:		%s
-p99:		%s
-p999:		%s
+p50: 		%s%s
+p75:		%s%s
+p95:		%s%s
+p99:		%s%s
+p999:		%s%s
+`,
+		m.Samples,
+		m.Count,
+		corrected,
+		m.Time.Cumulative,
+		m.Time.HMean,
+		m.Time.Avg,
+		m.Time.P50, m.interval("p50"),
+		m.Time.P75, m.interval("p75"),
+		m.Time.P95, m.interval("p95"),
+		m.Time.P99, m.interval("p99"),
+		m.Time.P999, m.interval("p999"))
+
+	for _, p := range m.Percentiles {
+		l := p.Label() + ":"
+		// Align values with a tab width of 8.
+		if len(l) < 8 {
+			l += "\t"
+		}
+		s += fmt.Sprintf("\n%s\t%s%s", l, p.Value, m.interval(p.Label()))
+	}
+
+	s += fmt.Sprintf(`
 Long 5%%:	%s
 Short 5%%:	%s
 Max:		%s
@@ -141,16 +552,6 @@ Min:		%s
 Range:		%s
 StdDev:		%s
 Rate/sec.:	%.2f`,
-		m.Samples,
-		m.Count,
-		m.Time.Cumulative,
-		m.Time.HMean,
-		m.Time.Avg,
-		m.Time.P50,
-		m.Time.P75,
-		m.Time.P95,
-		m.Time.P99,
-		m.Time.P999,
 		m.Time.Long5p,
 		m.Time.Short5p,
 		m.Time.Max,
@@ -158,6 +559,60 @@ Rate/sec.:	%.2f`,
 		m.Time.Range,
 		m.Time.StdDev,
 		m.Rate.Second)
+
+	if e := m.Extended; e != nil {
+		s += fmt.Sprintf(`
+GeoMean:	%s
+MAD:		%s
+IQR:		%s
+Skewness:	%.3f
+Kurtosis:	%.3f
+CV:		%.3f`,
+			e.GeoMean,
+			e.MAD,
+			e.IQR,
+			e.Skewness,
+			e.Kurtosis,
+			e.CV)
+	}
+
+	if o := m.Outliers; o != nil {
+		s += fmt.Sprintf("\nOutliers:\t%d (< %s or > %s)", o.Count, o.Low, o.High)
+	}
+
+	if t := m.Trimmed; t != nil {
+		s += fmt.Sprintf(`
+Trim Avg.:	%s
+Trim StdDev:	%s
+Wins. Avg.:	%s
+Wins. StdDev:	%s`,
+			t.Avg,
+			t.StdDev,
+			t.WinsorizedAvg,
+			t.WinsorizedStdDev)
+	}
+
+	if a := m.Apdex; a != nil {
+		s += fmt.Sprintf("\nApdex:\t\t%.2f (T=%s, %d satisfied, %d tolerating, %d frustrated)",
+			a.Score, a.T, a.Satisfied, a.Tolerating, a.Frustrated)
+	}
+
+	if m.Success == nil || m.Failure == nil {
+		return s
+	}
+
+	return s + fmt.Sprintf(`
+Errors:		%d (%.2f%%)
+Success p50:	%s
+Success p99:	%s
+Failure p50:	%s
+Failure p99:	%s`,
+		m.Errors,
+		m.ErrorRate*100,
+		m.Success.Time.P50,
+		m.Success.Time.P99,
+		m.Failure.Time.P50,
+		m.Failure.Time.P99)
 }
 
 // JSON returns a *Metrics as
@@ -172,6 +627,17 @@ func (m *Metrics) JSON() string {
 // for the JSON() method. This is exported as a
 // requirement but not intended for end users.
 func (m *Metrics) MarshalJSON() ([]byte, error) {
+	var percentiles []struct {
+		P     float64
+		Value string
+	}
+	for _, p := range m.Percentiles {
+		percentiles = append(percentiles, struct {
+			P     float64
+			Value string
+		}{P: p.P, Value: p.Value.String()})
+	}
+
 	return json.Marshal(&struct {
 		Time struct {
 			Cumulative string
@@ -189,11 +655,28 @@ func (m *Metrics) MarshalJSON() ([]byte, error) {
 			Range      string
 			StdDev     string
 		}
-		Rate struct {
+		Percentiles []struct {
+			P     float64
+			Value string
+		} `json:",omitempty"`
+		Intervals  map[string]Interval `json:",omitempty"`
+		Confidence float64             `json:",omitempty"`
+		Extended   *ExtendedStats      `json:",omitempty"`
+		Outliers   *Outliers           `json:",omitempty"`
+		Trimmed    *Trimmed            `json:",omitempty"`
+		Apdex      *Apdex              `json:",omitempty"`
+		Rate       struct {
 			Second float64
 		}
 		Samples   int
 		Count     int
+		Mode      string
+		Corrected bool     `json:",omitempty"`
+		Errors    int      `json:",omitempty"`
+		ErrorRate float64  `json:",omitempty"`
+		Success   *Metrics `json:",omitempty"`
+		Failure   *Metrics `json:",omitempty"`
+		Bins      Bins     `json:",omitempty"`
 		Histogram *Histogram
 	}{
 		Time: struct {
@@ -227,12 +710,26 @@ func (m *Metrics) MarshalJSON() ([]byte, error) {
 			Range:      m.Time.Range.String(),
 			StdDev:     m.Time.StdDev.String(),
 		},
+		Percentiles: percentiles,
+		Intervals:   m.Intervals,
+		Confidence:  m.Confidence,
+		Extended:    m.Extended,
+		Outliers:    m.Outliers,
+		Trimmed:     m.Trimmed,
+		Apdex:       m.Apdex,
 		Rate: struct{ Second float64 }{
 			Second: m.Rate.Second,
 		},
+		Bins:      m.Bins,
 		Histogram: m.Histogram,
 		Samples:   m.Samples,
 		Count:     m.Count,
+		Mode:      m.Mode.String(),
+		Corrected: m.Corrected,
+		Errors:    m.Errors,
+		ErrorRate: m.ErrorRate,
+		Success:   m.Success,
+		Failure:   m.Failure,
 	})
 }
 
@@ -1,6 +1,10 @@
 package tachymeter_test
 
 import (
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,6 +35,37 @@ func BenchmarkAddTimeSampling(b *testing.B) {
 	}
 }
 
+func BenchmarkAddTimeParallel(b *testing.B) {
+	b.StopTimer()
+
+	ta := tachymeter.New(&tachymeter.Config{Size: 100})
+	d := time.Millisecond
+
+	b.StartTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ta.AddTime(d)
+		}
+	})
+}
+
+func BenchmarkAddTimeSharded(b *testing.B) {
+	b.StopTimer()
+
+	ta := tachymeter.New(&tachymeter.Config{
+		Size:   100,
+		Shards: runtime.GOMAXPROCS(0),
+	})
+	d := time.Millisecond
+
+	b.StartTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ta.AddTime(d)
+		}
+	})
+}
+
 func TestReset(t *testing.T) {
 	ta := tachymeter.New(&tachymeter.Config{Size: 3})
 
@@ -63,3 +98,155 @@ func TestSetWallTime(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestConcurrentAccess(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		ta := tachymeter.New(&tachymeter.Config{Size: 50, Shards: shards})
+
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+
+		// Writers.
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < 1000; n++ {
+					ta.AddTime(time.Millisecond)
+				}
+			}()
+		}
+
+		// Readers.
+		var rwg sync.WaitGroup
+		rwg.Add(1)
+		go func() {
+			defer rwg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if m := ta.Calc(); m.Count > 0 && m.Time.Min != time.Millisecond {
+					t.Errorf("Expected 1ms, got %s\n", m.Time.Min)
+				}
+				ta.SetWallTime(time.Second)
+			}
+		}()
+
+		wg.Wait()
+		close(done)
+		rwg.Wait()
+
+		if m := ta.Calc(); m.Count != 4000 {
+			t.Errorf("Expected 4000, got %d\n", m.Count)
+		}
+
+		// Reset while writers are running.
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				ta.AddTime(time.Millisecond)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				ta.Reset()
+			}
+		}()
+		wg.Wait()
+
+		if m := ta.Calc(); m.Count > 1000 {
+			t.Errorf("Expected at most 1000, got %d\n", m.Count)
+		}
+	}
+}
+
+func TestAddTimeResult(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 10})
+
+	ta.AddTimeResult(10*time.Millisecond, nil)
+	ta.AddTimeResult(20*time.Millisecond, nil)
+	ta.AddTimeResult(30*time.Millisecond, nil)
+	ta.AddTimeResult(time.Millisecond, errors.New("timeout"))
+
+	metrics := ta.Calc()
+
+	if metrics.Count != 4 {
+		t.Errorf("Expected 4, got %d\n", metrics.Count)
+	}
+
+	if metrics.Errors != 1 {
+		t.Errorf("Expected 1, got %d\n", metrics.Errors)
+	}
+
+	if metrics.ErrorRate != 0.25 {
+		t.Errorf("Expected 0.25, got %f\n", metrics.ErrorRate)
+	}
+
+	if metrics.Success.Count != 3 || metrics.Success.Time.Min != 10*time.Millisecond {
+		t.Errorf("Unexpected success metrics: %+v\n", metrics.Success)
+	}
+
+	if metrics.Failure.Count != 1 || metrics.Failure.Time.Max != time.Millisecond {
+		t.Errorf("Unexpected failure metrics: %+v\n", metrics.Failure)
+	}
+
+	if !strings.Contains(metrics.String(), "Errors:\t\t1 (25.00%)") {
+		t.Errorf("Expected error line, got %s\n", metrics.String())
+	}
+
+	if !strings.Contains(metrics.JSON(), `"Errors":1,"ErrorRate":0.25,"Success":{`) {
+		t.Errorf("Expected outcomes in JSON, got %s\n", metrics.JSON())
+	}
+
+	ta.Reset()
+
+	if m := ta.Calc(); m.Errors != 0 || m.Success.Count != 0 {
+		t.Error("Expected outcomes to be reset")
+	}
+
+	// Outcomes are omitted when not tracked.
+	plain := tachymeter.New(&tachymeter.Config{Size: 10})
+	plain.AddTime(time.Millisecond)
+
+	if m := plain.Calc(); m.Success != nil || strings.Contains(m.JSON(), "Errors") {
+		t.Error("Expected no outcomes")
+	}
+}
+
+func TestAddTimeCorrected(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{Size: 100, Shards: shards})
+
+		ta.AddTimeCorrected(15*time.Millisecond, 10*time.Millisecond)
+		if metrics := ta.Calc(); metrics.Count != 1 || metrics.Corrected {
+			t.Errorf("Expected 1 uncorrected event, got %d %t\n", metrics.Count, metrics.Corrected)
+		}
+
+		// Back-fills 35, 25 and 15ms.
+		ta.AddTimeCorrected(45*time.Millisecond, 10*time.Millisecond)
+
+		metrics := ta.Calc()
+		if metrics.Count != 5 {
+			t.Errorf("Expected 5, got %d\n", metrics.Count)
+		}
+
+		if metrics.Time.Cumulative != 135*time.Millisecond {
+			t.Errorf("Expected 135ms, got %s\n", metrics.Time.Cumulative)
+		}
+
+		if !metrics.Corrected || !strings.HasPrefix(metrics.String(), "5 samples of 5 events (corrected)\n") {
+			t.Errorf("Expected a corrected result, got %s\n", metrics.String())
+		}
+
+		ta.Reset()
+		if ta.Calc().Corrected {
+			t.Error("Expected Reset to clear the corrected flag")
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package tachymeter
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// centroid is a t-digest cluster of events
+// summarized by their mean and count.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// tdigest is a merging t-digest of event
+// durations. Events are clustered into centroids
+// whose maximum size is bounded by a scale function
+// that keeps clusters near the tails small, which
+// yields accurate extreme percentiles in memory
+// proportional to the compression parameter.
+// See https://github.com/tdunning/t-digest.
+type tdigest struct {
+	compression float64
+	centroids   []centroid // Merged centroids, sorted by mean.
+	buffer      []centroid // Unmerged events.
+	bufferSize  int        // Events buffered before compressing.
+	count       uint64
+	min, max    float64
+}
+
+// maxCompression is the highest
+// supported t-digest compression.
+const maxCompression = 1e5
+
+// newTDigest returns a *tdigest configured
+// with the compression specified in c.
+func newTDigest(c *Config) *tdigest {
+	compression := c.Compression
+	switch {
+	case !(compression > 0):
+		compression = 100
+	case compression > maxCompression:
+		compression = maxCompression
+	}
+
+	// The buffer grows as events are added
+	// rather than being allocated up front.
+	return &tdigest{
+		compression: compression,
+		bufferSize:  int(compression) * 5,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (d *tdigest) add(t time.Duration) {
+	v := float64(t)
+	d.buffer = append(d.buffer, centroid{mean: v, weight: 1})
+	d.count++
+
+	if v < d.min {
+		d.min = v
+	}
+	if v > d.max {
+		d.max = v
+	}
+
+	if len(d.buffer) >= d.bufferSize {
+		d.compress()
+	}
+}
+
+// k is the t-digest k1 scale function, mapping
+// quantile q to a scale where each centroid may
+// span at most one unit.
+func (d *tdigest) k(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// kInv is the inverse of k.
+func (d *tdigest) kInv(k float64) float64 {
+	return (math.Sin(k*2*math.Pi/d.compression) + 1) / 2
+}
+
+// compress merges buffered events
+// into the centroids.
+func (d *tdigest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+
+	all := append(d.buffer, d.centroids...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	var total float64
+	for _, c := range all {
+		total += c.weight
+	}
+
+	merged := make([]centroid, 0, len(d.centroids)+1)
+	cur := all[0]
+	var sofar float64
+	limit := d.kInv(d.k(0)+1) * total
+
+	for _, c := range all[1:] {
+		if sofar+cur.weight+c.weight <= limit {
+			// Fold c into the current centroid.
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+
+		merged = append(merged, cur)
+		sofar += cur.weight
+		limit = d.kInv(d.k(sofar/total)+1) * total
+		cur = c
+	}
+
+	d.centroids = append(merged, cur)
+	d.buffer = d.buffer[:0]
+}
+
+// quantile returns the estimated duration at
+// quantile q by interpolating between centroids.
+func (d *tdigest) quantile(q float64) time.Duration {
+	d.compress()
+
+	cs := d.centroids
+	switch {
+	case len(cs) == 0:
+		return 0
+	case q <= 0:
+		return time.Duration(d.min)
+	case q >= 1:
+		return time.Duration(d.max)
+	case len(cs) == 1:
+		return time.Duration(cs[0].mean)
+	}
+
+	var total float64
+	for _, c := range cs {
+		total += c.weight
+	}
+
+	target := q * total
+
+	// Between the min and the first centroid.
+	if target < cs[0].weight/2 {
+		return time.Duration(d.min + target/(cs[0].weight/2)*(cs[0].mean-d.min))
+	}
+
+	// Between adjacent centroid centers.
+	cumulative := cs[0].weight / 2
+	for i := 0; i < len(cs)-1; i++ {
+		step := (cs[i].weight + cs[i+1].weight) / 2
+		if target < cumulative+step {
+			f := (target - cumulative) / step
+			return time.Duration(cs[i].mean + f*(cs[i+1].mean-cs[i].mean))
+		}
+		cumulative += step
+	}
+
+	// Between the last centroid and the max.
+	last := cs[len(cs)-1]
+	f := (target - cumulative) / (last.weight / 2)
+	if f > 1 {
+		f = 1
+	}
+
+	return time.Duration(last.mean + f*(d.max-last.mean))
+}
+
+func (d *tdigest) reset() {
+	d.centroids = d.centroids[:0]
+	d.buffer = d.buffer[:0]
+	d.count = 0
+	d.min, d.max = math.Inf(1), math.Inf(-1)
+}
+
+func (d *tdigest) clone() recorder {
+	c := *d
+	c.centroids = append([]centroid(nil), d.centroids...)
+	c.buffer = make([]centroid, len(d.buffer), cap(d.buffer))
+	copy(c.buffer, d.buffer)
+
+	return &c
+}
+
+func (d *tdigest) merge(o recorder) {
+	od := o.(*tdigest)
+	d.buffer = append(d.buffer, od.buffer...)
+	d.buffer = append(d.buffer, od.centroids...)
+	d.count += od.count
+
+	if od.min < d.min {
+		d.min = od.min
+	}
+	if od.max > d.max {
+		d.max = od.max
+	}
+
+	d.compress()
+}
+
+func (d *tdigest) encode(e *encoder) {
+	d.compress()
+
+	e.uvarint(d.count)
+	e.float(d.min)
+	e.float(d.max)
+	e.uvarint(uint64(len(d.centroids)))
+	for _, c := range d.centroids {
+		e.float(c.mean)
+		e.float(c.weight)
+	}
+}
+
+func (d *tdigest) decode(dec *decoder) {
+	d.reset()
+	d.count = dec.uvarint()
+	d.min = dec.float()
+	d.max = dec.float()
+
+	for n := dec.length(); n > 0; n-- {
+		d.centroids = append(d.centroids, centroid{
+			mean:   dec.float(),
+			weight: dec.float(),
+		})
+	}
+}
+
+// summary returns the centroid means weighted by
+// their counts. Percentiles are interpolated by
+// the digest.
+func (d *tdigest) summary() *summary {
+	d.compress()
+
+	times := make(timeSlice, len(d.centroids))
+	weights := make([]float64, len(d.centroids))
+	for i, c := range d.centroids {
+		times[i] = time.Duration(c.mean)
+		weights[i] = c.weight
+	}
+
+	s := newSummary(times, weights, d.count)
+	s.samples = int(d.count)
+	s.quantile = d.quantile
+
+	return s
+}
@@ -0,0 +1,95 @@
+package tachymeter_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestTDigest(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Mode:        tachymeter.ModeTDigest,
+			Compression: 200,
+			Shards:      shards,
+		})
+
+		// 1µs..100ms in 1µs steps, shuffled.
+		r := rand.New(rand.NewSource(1))
+		for _, i := range r.Perm(100000) {
+			ta.AddTime(time.Duration(i+1) * time.Microsecond)
+		}
+
+		metrics := ta.Calc()
+
+		if metrics.Count != 100000 {
+			t.Errorf("Expected 100000, got %d\n", metrics.Count)
+		}
+
+		if metrics.Time.Min != time.Microsecond {
+			t.Errorf("Expected 1µs, got %s\n", metrics.Time.Min)
+		}
+
+		if metrics.Time.Max != 100*time.Millisecond {
+			t.Errorf("Expected 100ms, got %s\n", metrics.Time.Max)
+		}
+
+		expected := map[string][2]time.Duration{
+			"p50":  {metrics.Time.P50, 50 * time.Millisecond},
+			"p95":  {metrics.Time.P95, 95 * time.Millisecond},
+			"p99":  {metrics.Time.P99, 99 * time.Millisecond},
+			"p999": {metrics.Time.P999, 99900 * time.Microsecond},
+			"avg":  {metrics.Time.Avg, 50000500 * time.Nanosecond},
+		}
+
+		for k, v := range expected {
+			if !within(v[0], v[1], 0.005) {
+				t.Errorf("%s: expected %s, got %s\n", k, v[1], v[0])
+			}
+		}
+
+		ta.Reset()
+
+		if ta.Calc().Count != 0 {
+			t.Error("Expected 0 after reset")
+		}
+	}
+}
+
+func TestTDigestSmall(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeTDigest})
+
+	ta.AddTime(time.Millisecond)
+	ta.AddTime(2 * time.Millisecond)
+	ta.AddTime(3 * time.Millisecond)
+
+	metrics := ta.Calc()
+
+	if metrics.Time.P50 != 2*time.Millisecond {
+		t.Errorf("Expected 2ms, got %s\n", metrics.Time.P50)
+	}
+
+	if metrics.Time.Cumulative != 6*time.Millisecond {
+		t.Errorf("Expected 6ms, got %s\n", metrics.Time.Cumulative)
+	}
+}
+
+func TestTDigestLargeCompression(t *testing.T) {
+	for _, compression := range []float64{1e15, math.Inf(1), math.NaN()} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Mode:        tachymeter.ModeTDigest,
+			Compression: compression,
+		})
+
+		for i := 1; i <= 1000; i++ {
+			ta.AddTime(time.Duration(i) * time.Millisecond)
+		}
+
+		if p50 := ta.Calc().Time.P50; !within(p50, 500*time.Millisecond, 0.01) {
+			t.Errorf("%g: Expected ~500ms, got %s\n", compression, p50)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package tachymeter
+
+import (
+	"time"
+)
+
+// Timer measures the duration of a single
+// event for a Tachymeter.
+type Timer struct {
+	m     *Tachymeter
+	start time.Time
+}
+
+// Start returns a Timer started
+// at the current time.
+func (m *Tachymeter) Start() Timer {
+	return Timer{m: m, start: time.Now()}
+}
+
+// Stop adds the time elapsed since the Timer
+// was started to its Tachymeter and returns it.
+func (t Timer) Stop() time.Duration {
+	d := time.Since(t.start)
+	t.m.AddTime(d)
+
+	return d
+}
+
+// Time calls f and adds the time
+// it took to the Tachymeter.
+func (m *Tachymeter) Time(f func()) {
+	t := m.Start()
+	f()
+	t.Stop()
+}
+
+// TimeErr calls f and adds the time it took
+// to the Tachymeter along with its outcome, as
+// with AddTimeResult. The error returned by f
+// is returned.
+func (m *Tachymeter) TimeErr(f func() error) error {
+	start := time.Now()
+	err := f()
+	m.AddTimeResult(time.Since(start), err)
+
+	return err
+}
@@ -0,0 +1,74 @@
+package tachymeter_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestTimer(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 3})
+
+	timer := ta.Start()
+	time.Sleep(time.Millisecond)
+	d := timer.Stop()
+
+	if d < time.Millisecond {
+		t.Errorf("Expected at least 1ms, got %s\n", d)
+	}
+
+	if ta.Times[0] != d {
+		t.Errorf("Expected %s, got %s\n", d, ta.Times[0])
+	}
+}
+
+func TestTime(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 3})
+
+	var called bool
+	ta.Time(func() { called = true })
+
+	if !called {
+		t.Error("Expected f to be called")
+	}
+
+	errTest := errors.New("test")
+	if err := ta.TimeErr(func() error { return errTest }); err != errTest {
+		t.Errorf("Expected errTest, got %v\n", err)
+	}
+
+	if ta.Count != 2 {
+		t.Errorf("Expected 2, got %d\n", ta.Count)
+	}
+
+	// TimeErr records the outcome.
+	if m := ta.Calc(); m.Errors != 1 {
+		t.Errorf("Expected 1, got %d\n", m.Errors)
+	}
+}
+
+func TestTimerAllocs(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 100})
+	f := func() {}
+	fe := func() error { return nil }
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ta.Start().Stop()
+		ta.Time(f)
+		ta.TimeErr(fe)
+	})
+
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocs, got %.1f\n", allocs)
+	}
+}
+
+func BenchmarkTimer(b *testing.B) {
+	ta := tachymeter.New(&tachymeter.Config{Size: 100})
+
+	for i := 0; i < b.N; i++ {
+		ta.Start().Stop()
+	}
+}
@@ -0,0 +1,195 @@
+package tachymeter
+
+import (
+	"time"
+)
+
+// windowBuckets is the number of sub-buckets
+// a timeWindow's duration is divided into.
+const windowBuckets = 10
+
+// windowBucket holds the timestamped events
+// recorded during a single sub-bucket interval.
+type windowBucket struct {
+	epoch  int64 // Start time / bucket width.
+	times  timeSlice
+	stamps []int64 // UnixNano timestamps of times.
+	count  uint64
+}
+
+// timeWindow is a sliding window of events
+// observed over the trailing window duration.
+// Events are held in rotating sub-buckets that
+// are reused once they've aged out of the window.
+type timeWindow struct {
+	window  time.Duration
+	width   int64 // Bucket width in nanoseconds.
+	limit   int   // Max samples per bucket; 0 for no limit.
+	buckets []windowBucket
+}
+
+// newTimeWindow returns a *timeWindow spanning
+// the Window specified in c, defaulting to 1m. If
+// c.Size is set, it bounds the number of samples
+// held across n shards.
+func newTimeWindow(c *Config, n int) *timeWindow {
+	window := c.Window
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	w := &timeWindow{
+		window: window,
+		width:  int64(window) / windowBuckets,
+		// An additional bucket covers the window
+		// as the oldest bucket partially ages out.
+		buckets: make([]windowBucket, windowBuckets+1),
+	}
+
+	if w.width < 1 {
+		w.width = 1
+	}
+
+	if c.Size > 0 {
+		w.limit = shardSize(c.Size, n*windowBuckets)
+	}
+
+	for i := range w.buckets {
+		w.buckets[i].epoch = -1
+	}
+
+	return w
+}
+
+func (w *timeWindow) add(t time.Duration) {
+	now := time.Now().UnixNano()
+	e := now / w.width
+	b := &w.buckets[e%int64(len(w.buckets))]
+
+	// Rotate out a stale bucket.
+	if b.epoch != e {
+		b.epoch = e
+		b.times = b.times[:0]
+		b.stamps = b.stamps[:0]
+		b.count = 0
+	}
+
+	if w.limit == 0 || len(b.times) < w.limit {
+		b.times = append(b.times, t)
+		b.stamps = append(b.stamps, now)
+	} else {
+		i := b.count % uint64(w.limit)
+		b.times[i] = t
+		b.stamps[i] = now
+	}
+
+	b.count++
+}
+
+func (w *timeWindow) reset() {
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		b.epoch = -1
+		b.times = b.times[:0]
+		b.stamps = b.stamps[:0]
+		b.count = 0
+	}
+}
+
+func (w *timeWindow) clone() recorder {
+	c := *w
+	c.buckets = make([]windowBucket, len(w.buckets))
+	for i, b := range w.buckets {
+		c.buckets[i] = windowBucket{
+			epoch:  b.epoch,
+			times:  append(timeSlice(nil), b.times...),
+			stamps: append([]int64(nil), b.stamps...),
+			count:  b.count,
+		}
+	}
+
+	return &c
+}
+
+// merge appends the buckets of o to w. The
+// merged window isn't intended for further
+// writes.
+func (w *timeWindow) merge(o recorder) {
+	w.buckets = append(w.buckets, o.clone().(*timeWindow).buckets...)
+}
+
+func (w *timeWindow) encode(e *encoder) {
+	e.uvarint(uint64(len(w.buckets)))
+	for _, b := range w.buckets {
+		e.varint(b.epoch)
+		e.uvarint(b.count)
+		e.uvarint(uint64(len(b.times)))
+		for i, t := range b.times {
+			e.duration(t)
+			e.varint(b.stamps[i])
+		}
+	}
+}
+
+func (w *timeWindow) decode(d *decoder) {
+	if d.length() != len(w.buckets) {
+		d.fail()
+		return
+	}
+
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		b.epoch = d.varint()
+		b.count = d.uvarint()
+
+		n := d.length()
+		if w.limit > 0 && n > w.limit {
+			d.fail()
+			return
+		}
+
+		b.times = make(timeSlice, n)
+		b.stamps = make([]int64, n)
+		for j := 0; j < n; j++ {
+			b.times[j] = d.duration()
+			b.stamps[j] = d.varint()
+		}
+	}
+}
+
+func (w *timeWindow) summary() *summary {
+	return w.summaryWithin(time.Now(), w.window)
+}
+
+// summaryWithin returns a summary of events
+// observed in the d duration preceding now.
+func (w *timeWindow) summaryWithin(now time.Time, d time.Duration) *summary {
+	cutoff := now.UnixNano() - int64(d)
+
+	var times timeSlice
+	var count float64
+
+	// Buckets that have aged out but haven't been
+	// reused yet hold only stamps beyond the cutoff.
+	for _, b := range w.buckets {
+		if len(b.times) == 0 {
+			continue
+		}
+
+		var n int
+		for i, s := range b.stamps {
+			if s > cutoff {
+				times = append(times, b.times[i])
+				n++
+			}
+		}
+
+		// Bucket counts include events overwritten
+		// once the sample limit is reached; the portion
+		// within the window is estimated from the
+		// remaining samples.
+		count += float64(b.count) * float64(n) / float64(len(b.times))
+	}
+
+	return newSummary(times, nil, uint64(count+0.5))
+}
@@ -0,0 +1,87 @@
+package tachymeter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+func TestTimeWindow(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		ta := tachymeter.New(&tachymeter.Config{
+			Window: 200 * time.Millisecond,
+			Shards: shards,
+		})
+
+		for i := 0; i < 100; i++ {
+			ta.AddTime(time.Second)
+		}
+
+		metrics := ta.Calc()
+
+		if metrics.Mode != tachymeter.ModeTimeWindow {
+			t.Errorf("Expected timewindow, got %s\n", metrics.Mode)
+		}
+
+		if metrics.Count != 100 {
+			t.Errorf("Expected 100, got %d\n", metrics.Count)
+		}
+
+		// Age the first events out of the window.
+		time.Sleep(250 * time.Millisecond)
+
+		for i := 0; i < 50; i++ {
+			ta.AddTime(time.Millisecond)
+		}
+
+		metrics = ta.Calc()
+
+		if metrics.Count != 50 {
+			t.Errorf("Expected 50, got %d\n", metrics.Count)
+		}
+
+		if metrics.Time.Max != time.Millisecond {
+			t.Errorf("Expected 1ms, got %s\n", metrics.Time.Max)
+		}
+
+		time.Sleep(250 * time.Millisecond)
+
+		if c := ta.Calc().Count; c != 0 {
+			t.Errorf("Expected 0, got %d\n", c)
+		}
+	}
+}
+
+func TestTimeWindowSize(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{
+		Window: time.Minute,
+		Size:   100,
+	})
+
+	for i := 0; i < 1000; i++ {
+		ta.AddTime(time.Millisecond)
+	}
+
+	metrics := ta.Calc()
+
+	if metrics.Count != 1000 {
+		t.Errorf("Expected 1000, got %d\n", metrics.Count)
+	}
+
+	if metrics.Samples > 100 {
+		t.Errorf("Expected at most 100, got %d\n", metrics.Samples)
+	}
+}
+
+func TestTimeWindowDefault(t *testing.T) {
+	ta := tachymeter.New(&tachymeter.Config{Mode: tachymeter.ModeTimeWindow})
+
+	for i := 0; i < 10; i++ {
+		ta.AddTime(time.Millisecond)
+	}
+
+	if metrics := ta.Calc(); metrics.Count != 10 {
+		t.Errorf("Expected 10, got %d\n", metrics.Count)
+	}
+}